@@ -30,12 +30,15 @@
 
         };
 
+        # vendorHash covers the output of `go mod vendor`, which only holds the packages a tool imports.
+        # It changes when a tool imports another package of a module that is already in go.mod,
+        # e.g. go-osstat/loadavg and go-osstat/memory in hasenfetch, even if go.mod and go.sum stay the same.
         tools = builtins.mapAttrs (_: tool: pkgs.buildGoModule tool) {
-          hasenfetch = make_tool "hasenfetch" "sha256-L5FZufDpwhv9rFdB5ELeJpROrtnQVN3aaTww9u9DY8A=";
+          hasenfetch = make_tool "hasenfetch" "sha256-hOh/sCTSeMJvfTPHyDZhBHbaRLP2MJdgl3soUK+f7g8=";
           hex = make_tool "hex" "sha256-+aMFr9k1itFXWCGh3Z2jy/XyiS/l303eEVf8kBCBj5M=";
           jenv = make_tool "jenv" null;
           jo = make_tool "jo" "sha256-9gO00c3D846SJl5dbtfj0qasmONLNxU/7V1TG6QEaxM=";
-          nibs = make_tool "nibs" "sha256-lcjv0tCFPga4n2lc5rRXe5A1jIDLScKEsWrG0a/Sftc=";
+          nibs = make_tool "nibs" "sha256-R8+uGcX4ODg7o0g1/1e2cMs53grIDOeCHfgqN2aa7KI=";
           obs = (make_tool "obs" "sha256-+Ezs6+YOOIESXrQneAQAsfvo3L6LwIiBx3LEybgEqBw=") // {
             doCheck = false;
           };
@@ -47,7 +50,7 @@
           ssl-expiry = make_tool "ssl-expiry" null;
           timezone = make_tool "timezone" "sha256-JFvC9V0xS8SZSdLsOtpyTrFzXjYAOaPQaJHdcnJzK3s=";
           urlencode = make_tool "urlencode" "sha256-OEXvKQ/dBxhz6/pbQNDYIjBf3O0x36ZE3Se/FqEgYRg=";
-          uuid = make_tool "uuid" "sha256-PfJNr7t/27PSnwIwFv0kHV3f+er0fpHwqddS8yS7ofo=";
+          uuid = make_tool "uuid" "sha256-mGKxBRU5TPgdmiSx0DHEd0Ys8gsVD/YdBfbDdSVpC3U=";
           xls-format = make_tool "xls-format" "sha256-IvH6IKMmJ/yM7ZbkNdVkmiuRlTJtXy1eHh5mCKolfKk=";
         };
       in
@@ -0,0 +1,152 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"runtime"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// arts are the built-in ASCII arts
+var arts = map[string][]string{
+	"bunny": {
+		``,
+		`(\__/)`,
+		`(='.'=)`,
+		`(")_(")`,
+	},
+	"tux": {
+		`    .--.`,
+		`   |o_o |`,
+		`   |:_/ |`,
+		`  //   \ \`,
+		` (|     | )`,
+		`/'\_   _/'\`,
+		`\___)=(___/`,
+	},
+	"arch": {
+		`      /\`,
+		`     /  \`,
+		`    /\   \`,
+		`   /  __  \`,
+		`  /  (  )  \`,
+		` / __|  |__ \`,
+		`/.'        '.\`,
+	},
+	"debian": {
+		`  _____`,
+		` /  __ \`,
+		`|  /    |`,
+		`|  \___-`,
+		`-_`,
+		`  --_`,
+	},
+	"ubuntu": {
+		`         _`,
+		`     ---(_)`,
+		` _/  ---  \`,
+		`(_) |   |`,
+		`  \  --- _/`,
+		`     ---(_)`,
+	},
+	"fedora": {
+		`      _____`,
+		`     /   __)\`,
+		`     |  /  \ \`,
+		`  ___|  |__/ /`,
+		` / (_    _)_/`,
+		`/ /  |  |`,
+		`\ \__/  |`,
+		` \(_____/`,
+	},
+	"apple": {
+		`       .:'`,
+		`    __ :'__`,
+		` .'      '.`,
+		`:          :`,
+		`:          :`,
+		` '.      .'`,
+		`   '.__.'`,
+	},
+}
+
+// defaultArt is the art without a config file or --art
+const defaultArt = "bunny"
+
+// artNames returns the names of the built-in arts, sorted
+func artNames() []string {
+	names := make([]string, 0, len(arts))
+	for name := range arts {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// loadArt returns the art from a file, a built-in art by name or, for "auto", the art of the distribution
+func loadArt(name string) ([]string, error) {
+	if name == "auto" {
+		return arts[autoArt(distroIDs(), runtime.GOOS)], nil
+	}
+	if art, ok := arts[name]; ok {
+		return art, nil
+	}
+	data, err := os.ReadFile(name)
+	if err != nil {
+		return nil, fmt.Errorf("%q is neither a built-in art (%s) nor a readable file: %w", name, strings.Join(artNames(), ", "), err)
+	}
+	text := strings.ReplaceAll(strings.TrimRight(string(data), "\r\n"), "\t", "    ")
+	return strings.Split(strings.ReplaceAll(text, "\r\n", "\n"), "\n"), nil
+}
+
+// autoArt picks the art for the distribution IDs from os-release, the first one with an art wins
+func autoArt(ids []string, goos string) string {
+	for _, id := range ids {
+		if _, ok := arts[id]; ok {
+			return id
+		}
+	}
+	switch goos {
+	case "darwin":
+		return "apple"
+	case "linux":
+		return "tux"
+	}
+	return defaultArt
+}
+
+// distroIDs returns the ID and ID_LIKE of os-release
+func distroIDs() []string {
+	release := readOSRelease()
+	return append([]string{release["ID"]}, strings.Fields(release["ID_LIKE"])...)
+}
+
+// layout puts the art left of the info, the shorter column is padded
+func layout(art, info []string, artColor string) []string {
+	width := 0
+	for _, line := range art {
+		width = max(width, utf8.RuneCountInString(line))
+	}
+	// keep a gap between art and info
+	if width > 0 {
+		width += 2
+	}
+
+	rows := max(len(art), len(info))
+	lines := make([]string, rows)
+	for i := range rows {
+		left := ""
+		if i < len(art) {
+			left = art[i]
+		}
+		if i >= len(info) {
+			lines[i] = colorize(artColor, strings.TrimRight(left, " "))
+			continue
+		}
+		padding := strings.Repeat(" ", width-utf8.RuneCountInString(left))
+		lines[i] = colorize(artColor, left) + padding + info[i]
+	}
+	return lines
+}
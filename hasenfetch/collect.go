@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"sync"
+	"text/tabwriter"
+	"time"
+)
+
+// notAvailable is shown for modules that timed out
+const notAvailable = "n/a"
+
+// result of collecting a single module
+type result struct {
+	module   module
+	value    any
+	err      error
+	timedOut bool
+	duration time.Duration
+}
+
+// collectAll collects the modules concurrently, each one gets timeout and all of them end at the deadline of ctx.
+// The results are in the order of the modules.
+func collectAll(ctx context.Context, selected []module, timeout time.Duration) []result {
+	results := make([]result, len(selected))
+	var wg sync.WaitGroup
+	for i, m := range selected {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = collect(ctx, m, timeout)
+		}()
+	}
+	wg.Wait()
+	return results
+}
+
+// collect runs a single module, a module that does not return in time is left behind
+func collect(ctx context.Context, m module, timeout time.Duration) result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	start := time.Now()
+	done := make(chan result, 1)
+	go func() {
+		value, err := m.collect(ctx)
+		done <- result{module: m, value: value, err: err}
+	}()
+
+	var r result
+	select {
+	case r = <-done:
+		// commands killed by the context fail instead of timing out
+		if r.err != nil && ctx.Err() != nil {
+			r = result{module: m, timedOut: true}
+		}
+	case <-ctx.Done():
+		r = result{module: m, timedOut: true}
+	}
+	r.duration = time.Since(start)
+	return r
+}
+
+// printBench writes how long each module took
+func printBench(w io.Writer, results []result, total time.Duration) error {
+	tw := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	for _, r := range results {
+		status := ""
+		switch {
+		case r.timedOut:
+			status = "timeout"
+		case r.err != nil:
+			status = "error"
+		}
+		fmt.Fprintf(tw, "%s\t%s", r.module.name, formatDuration(r.duration))
+		if status != "" {
+			fmt.Fprintf(tw, "\t%s", status)
+		}
+		fmt.Fprintln(tw)
+	}
+	fmt.Fprintf(tw, "total\t%s\n", formatDuration(total))
+	return tw.Flush()
+}
+
+func formatDuration(d time.Duration) string {
+	return fmt.Sprintf("%.1fms", float64(d)/float64(time.Millisecond))
+}
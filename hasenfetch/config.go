@@ -0,0 +1,153 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// config is read from a file with one "key = value" per line, lines starting with # are comments:
+//
+//	modules = title, distro, kernel, uptime, memory
+//	art = auto
+//	theme = sunset
+//	label_color = bold #ff9966
+//	timeout = 500ms
+type config struct {
+	Modules []string
+	// Art is a built-in art, a file or auto
+	Art   string
+	Theme string
+	// Colors override the colors of the theme
+	Colors theme
+	// Timeout is the time each module gets, Deadline the time all of them get
+	Timeout  time.Duration
+	Deadline time.Duration
+}
+
+const (
+	defaultTimeout  = time.Second
+	defaultDeadline = 2 * time.Second
+)
+
+// defaultConfigPath is hasenfetch/config in the user config directory, like ~/.config/hasenfetch/config
+func defaultConfigPath() string {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "hasenfetch", "config")
+}
+
+// loadConfig reads the config file at path, a missing file is only an error if required is set
+func loadConfig(path string, required bool) (config, error) {
+	cfg := config{
+		Modules:  defaultModules,
+		Art:      defaultArt,
+		Theme:    defaultTheme,
+		Timeout:  defaultTimeout,
+		Deadline: defaultDeadline,
+	}
+	if path == "" {
+		return cfg, nil
+	}
+	f, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) && !required {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(line, "=")
+		if !ok {
+			return cfg, fmt.Errorf("%s:%d: expected key = value", path, n)
+		}
+		key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+		switch key {
+		case "modules":
+			cfg.Modules = splitList(value)
+		case "art":
+			cfg.Art = value
+		case "theme":
+			cfg.Theme = value
+		case "art_color":
+			cfg.Colors.Art = value
+		case "title_color":
+			cfg.Colors.Title = value
+		case "label_color":
+			cfg.Colors.Label = value
+		case "value_color":
+			cfg.Colors.Value = value
+		case "timeout", "deadline":
+			d, err := time.ParseDuration(value)
+			if err != nil || d <= 0 {
+				return cfg, fmt.Errorf("%s:%d: invalid %s %q, expected a duration like 500ms", path, n, key, value)
+			}
+			if key == "timeout" {
+				cfg.Timeout = d
+			} else {
+				cfg.Deadline = d
+			}
+		default:
+			return cfg, fmt.Errorf("%s:%d: unknown key %q", path, n, key)
+		}
+	}
+	return cfg, scanner.Err()
+}
+
+// override applies the command line flags, empty values keep the setting of the config
+func (c config) override(modules, art, theme string, timeout, deadline time.Duration) config {
+	if modules != "" {
+		c.Modules = splitList(modules)
+	}
+	if art != "" {
+		c.Art = art
+	}
+	if theme != "" {
+		c.Theme = theme
+	}
+	if timeout > 0 {
+		c.Timeout = timeout
+	}
+	if deadline > 0 {
+		c.Deadline = deadline
+	}
+	return c
+}
+
+// theme returns the theme of the config with its colors applied
+func (c config) theme() (theme, error) {
+	t, ok := themes[c.Theme]
+	if !ok {
+		return t, fmt.Errorf("unknown theme %q, available: %s", c.Theme, strings.Join(themeNames(), ", "))
+	}
+	for _, override := range []struct{ color, target *string }{
+		{&c.Colors.Art, &t.Art},
+		{&c.Colors.Title, &t.Title},
+		{&c.Colors.Label, &t.Label},
+		{&c.Colors.Value, &t.Value},
+	} {
+		if *override.color != "" {
+			*override.target = *override.color
+		}
+	}
+	return t, t.validate()
+}
+
+// splitList splits a comma or space separated list
+func splitList(s string) []string {
+	return strings.FieldsFunc(s, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+}
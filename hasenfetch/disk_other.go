@@ -0,0 +1,12 @@
+//go:build !linux && !darwin && !freebsd
+
+package main
+
+import (
+	"fmt"
+	"runtime"
+)
+
+func diskUsage(path string) (uint64, uint64, error) {
+	return 0, 0, fmt.Errorf("not supported on %s", runtime.GOOS)
+}
@@ -0,0 +1,16 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import "syscall"
+
+// diskUsage returns the used and total bytes of the file system at path
+func diskUsage(path string) (uint64, uint64, error) {
+	var st syscall.Statfs_t
+	if err := syscall.Statfs(path, &st); err != nil {
+		return 0, 0, err
+	}
+	total := uint64(st.Blocks) * uint64(st.Bsize)
+	free := uint64(st.Bfree) * uint64(st.Bsize)
+	return total - free, total, nil
+}
@@ -2,11 +2,13 @@
 package main
 
 import (
+	"context"
+	"encoding/json"
+	"flag"
 	"fmt"
+	"io"
 	"log"
 	"os"
-	"os/user"
-	"runtime"
 	"strings"
 	"time"
 
@@ -23,49 +25,107 @@ func getSystemUptime() (time.Duration, error) {
 }
 
 func main() {
-	bunny := []string{
-		`         `,
-		`(\__/)   `,
-		`(='.'=)  `,
-		`(")_(")  `,
+	configPath := flag.String("config", "", "config file (default "+defaultConfigPath()+")")
+	moduleList := flag.String("modules", "", "comma separated modules to show, in order: "+strings.Join(moduleNames(), ", "))
+	jsonOutput := flag.Bool("json", false, "print the information as JSON")
+	artName := flag.String("art", "", "built-in art ("+strings.Join(artNames(), ", ")+"), auto for the art of the distribution or a file")
+	themeName := flag.String("theme", "", "color theme: "+strings.Join(themeNames(), ", "))
+	colorMode := flag.String("color", "auto", "colors: auto, always or never")
+	timeout := flag.Duration("timeout", 0, fmt.Sprintf("time each module gets (default %v)", defaultTimeout))
+	deadline := flag.Duration("deadline", 0, fmt.Sprintf("time all modules get together (default %v)", defaultDeadline))
+	bench := flag.Bool("bench", false, "print how long each module took to stderr")
+	flag.Parse()
+
+	cfg, err := loadConfig(defaultConfigPath(), false)
+	if *configPath != "" {
+		cfg, err = loadConfig(*configPath, true)
 	}
-
-	currentUser, err := user.Current()
 	if err != nil {
-		log.Printf("Error: %v\n", err)
-
-		return
+		log.Fatalf("Error: %v", err)
 	}
-
-	output := strings.Builder{}
-	output.WriteString(bunny[0])
-	output.WriteString(currentUser.Username)
-	output.WriteString("@")
-
-	hostname, err := os.Hostname()
+	cfg = cfg.override(*moduleList, *artName, *themeName, *timeout, *deadline)
+	selected, err := selectModules(cfg.Modules)
 	if err != nil {
-		log.Fatal(err)
+		log.Fatalf("Error: %v", err)
 	}
 
-	output.WriteString(hostname)
-	output.WriteString("\n")
-
-	output.WriteString(bunny[1])
-	output.WriteString(runtime.GOARCH)
-	output.WriteString("\n")
+	t, err := cfg.theme()
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	art, err := loadArt(cfg.Art)
+	if err != nil {
+		log.Fatalf("Error: %v", err)
+	}
+	if colorEnabled, err = useColor(*colorMode); err != nil {
+		log.Fatalf("Error: %v", err)
+	}
 
-	output.WriteString(bunny[2])
-	output.WriteString(runtime.GOOS)
-	output.WriteString("\n")
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Deadline)
+	start := time.Now()
+	results := collectAll(ctx, selected, cfg.Timeout)
+	total := time.Since(start)
+	cancel()
 
-	uptime, err := getSystemUptime()
+	if *jsonOutput {
+		err = printJSON(os.Stdout, results)
+	} else {
+		err = printInfo(os.Stdout, results, art, t)
+	}
 	if err != nil {
-		log.Println("Error:", err)
+		log.Fatalf("Error: %v", err)
+	}
+	if *bench {
+		if err := printBench(os.Stderr, results, total); err != nil {
+			log.Fatalf("Error: %v", err)
+		}
+	}
+}
 
-		return
+// printInfo writes the art next to the results
+func printInfo(w io.Writer, results []result, art []string, t theme) error {
+	var info []string
+	for _, r := range results {
+		text := notAvailable
+		if r.err != nil {
+			log.Printf("Error: %v\n", r.err)
+
+			continue
+		}
+		if !r.timedOut {
+			text = fmt.Sprint(r.value)
+		}
+		if text == "" {
+			continue
+		}
+		if r.module.label == "" {
+			info = append(info, colorize(t.Title, text))
+
+			continue
+		}
+		info = append(info, colorize(t.Label, fmt.Sprintf("%-9s", r.module.label))+colorize(t.Value, text))
 	}
 
-	output.WriteString(bunny[3] + fmt.Sprintf("%v\n", uptime))
+	output := strings.Builder{}
+	for _, line := range layout(art, info, t.Art) {
+		output.WriteString(line)
+		output.WriteString("\n")
+	}
 
-	log.Print(output.String())
+	_, err := io.WriteString(w, output.String())
+	return err
+}
+
+// printJSON writes the values of the modules as a JSON object keyed by module name, modules that failed or timed out are null
+func printJSON(w io.Writer, results []result) error {
+	values := make(map[string]any, len(results))
+	for _, r := range results {
+		if r.err != nil {
+			log.Printf("Error: %v\n", r.err)
+		}
+		values[r.module.name] = r.value
+	}
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+	return enc.Encode(values)
 }
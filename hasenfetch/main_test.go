@@ -0,0 +1,409 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFormatUptime(t *testing.T) {
+	tests := []struct {
+		in   time.Duration
+		want string
+	}{
+		{0, "0 seconds"},
+		{time.Second, "1 second"},
+		{45 * time.Second, "45 seconds"},
+		{time.Minute, "1 minute"},
+		{5*time.Minute + 12*time.Second, "5 minutes, 12 seconds"},
+		{time.Hour + 5*time.Second, "1 hour"},
+		{6*time.Hour + 52*time.Minute + 45*time.Second, "6 hours, 52 minutes"},
+		{24 * time.Hour, "1 day"},
+		{73*time.Hour + 12*time.Minute + 5123*time.Millisecond, "3 days, 1 hour"},
+		{49*time.Hour + 30*time.Minute, "2 days, 1 hour"},
+		{400 * 24 * time.Hour, "400 days"},
+	}
+	for _, tt := range tests {
+		if got := formatUptime(tt.in); got != tt.want {
+			t.Errorf("formatUptime(%v) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestParseOSRelease(t *testing.T) {
+	release := parseOSRelease(strings.NewReader(`# comment
+NAME="Arch Linux"
+PRETTY_NAME="Arch Linux"
+ID=arch
+VERSION_ID='2024.01'
+`))
+	if release["PRETTY_NAME"] != "Arch Linux" || release["ID"] != "arch" || release["VERSION_ID"] != "2024.01" {
+		t.Errorf("parseOSRelease() = %v", release)
+	}
+}
+
+// slow is a module that only returns when it is abandoned
+func slow(ctx context.Context) (any, error) {
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+// stuck is a module that ignores its context
+func stuck(context.Context) (any, error) {
+	time.Sleep(time.Hour)
+	return "awake", nil
+}
+
+func TestPrintJSON(t *testing.T) {
+	selected := []module{
+		{"uptime", "uptime", func(context.Context) (any, error) { return uptimeInfo(26*time.Hour + 3*time.Second), nil }},
+		{"memory", "memory", func(context.Context) (any, error) { return usage{Used: 1 << 30, Total: 4 << 30}, nil }},
+		{"ip", "ip", func(context.Context) (any, error) { return addresses{"192.168.1.2"}, nil }},
+		{"broken", "broken", func(context.Context) (any, error) { return nil, errors.New("no broken module") }},
+		{"slow", "slow", slow},
+	}
+	results := collectAll(context.Background(), selected, 10*time.Millisecond)
+	var out bytes.Buffer
+	if err := printJSON(&out, results); err != nil {
+		t.Fatal(err)
+	}
+
+	var got map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	want := map[string]any{
+		"uptime": map[string]any{"seconds": float64(93603), "text": "1 day, 2 hours"},
+		"memory": map[string]any{"used": float64(1 << 30), "total": float64(4 << 30)},
+		"ip":     []any{"192.168.1.2"},
+		"broken": nil,
+		"slow":   nil,
+	}
+	gotJSON, _ := json.Marshal(got)
+	wantJSON, _ := json.Marshal(want)
+	if !bytes.Equal(gotJSON, wantJSON) {
+		t.Errorf("printJSON() = %s, want %s", gotJSON, wantJSON)
+	}
+}
+
+func TestUsageString(t *testing.T) {
+	u := usage{Path: "/", Used: 3 << 29, Total: 16 << 30}
+	if got := u.String(); got != "1.5 GiB / 16.0 GiB (9%) /" {
+		t.Errorf("String() = %q", got)
+	}
+}
+
+func TestLayout(t *testing.T) {
+	colorEnabled = false
+	tests := []struct {
+		name string
+		art  []string
+		info []string
+		want []string
+	}{
+		{
+			name: "longer info",
+			art:  []string{"/\\", "\\/"},
+			info: []string{"a", "b", "c"},
+			want: []string{"/\\  a", "\\/  b", "    c"},
+		},
+		{
+			name: "longer art",
+			art:  []string{"(\\__/)", "(='.'=)", `(")_(")`},
+			info: []string{"a"},
+			want: []string{"(\\__/)   a", "(='.'=)", `(")_(")`},
+		},
+		{
+			name: "wide runes",
+			art:  []string{"äöü", "x"},
+			info: []string{"a", "b"},
+			want: []string{"äöü  a", "x    b"},
+		},
+		{
+			name: "no art",
+			info: []string{"a"},
+			want: []string{"a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := layout(tt.art, tt.info, "red")
+			if strings.Join(got, "\n") != strings.Join(tt.want, "\n") {
+				t.Errorf("layout() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSGR(t *testing.T) {
+	tests := []struct {
+		in      string
+		want    string
+		wantErr bool
+	}{
+		{in: "", want: ""},
+		{in: "bold", want: "1"},
+		{in: "red", want: "31"},
+		{in: "bright-cyan", want: "96"},
+		{in: "208", want: "38;5;208"},
+		{in: "bold #ff8000", want: "1;38;2;255;128;0"},
+		{in: "256", wantErr: true},
+		{in: "#ff80", wantErr: true},
+		{in: "pink", wantErr: true},
+	}
+	for _, tt := range tests {
+		got, err := sgr(tt.in)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("sgr(%q) = %q, %v, want %q", tt.in, got, err, tt.want)
+		}
+	}
+}
+
+func TestColorize(t *testing.T) {
+	defer func() { colorEnabled = false }()
+	colorEnabled = true
+	if got := colorize("bold red", "hi"); got != "\x1b[1;31mhi\x1b[0m" {
+		t.Errorf("colorize() = %q", got)
+	}
+	colorEnabled = false
+	if got := colorize("bold red", "hi"); got != "hi" {
+		t.Errorf("colorize() without colors = %q", got)
+	}
+}
+
+func TestUseColor(t *testing.T) {
+	t.Setenv("NO_COLOR", "1")
+	if on, _ := useColor("auto"); on {
+		t.Errorf("colors with NO_COLOR")
+	}
+	if on, _ := useColor("always"); !on {
+		t.Errorf("no colors with always")
+	}
+	t.Setenv("NO_COLOR", "")
+	// stdout of go test is no terminal
+	if on, _ := useColor("auto"); on {
+		t.Errorf("colors without a terminal")
+	}
+	if _, err := useColor("sometimes"); err == nil {
+		t.Errorf("no error for an invalid mode")
+	}
+}
+
+func TestAutoArt(t *testing.T) {
+	tests := []struct {
+		ids  []string
+		goos string
+		want string
+	}{
+		{[]string{"arch"}, "linux", "arch"},
+		{[]string{"pop", "ubuntu", "debian"}, "linux", "ubuntu"},
+		{[]string{"gentoo"}, "linux", "tux"},
+		{[]string{""}, "darwin", "apple"},
+		{[]string{""}, "windows", "bunny"},
+	}
+	for _, tt := range tests {
+		if got := autoArt(tt.ids, tt.goos); got != tt.want {
+			t.Errorf("autoArt(%v, %s) = %s, want %s", tt.ids, tt.goos, got, tt.want)
+		}
+	}
+}
+
+func TestCollectAll(t *testing.T) {
+	selected := []module{
+		{"fast", "fast", func(context.Context) (any, error) { return "quick", nil }},
+		{"slow", "slow", slow},
+		{"stuck", "stuck", stuck},
+		{"broken", "broken", func(context.Context) (any, error) { return nil, errors.New("broken") }},
+	}
+
+	start := time.Now()
+	results := collectAll(context.Background(), selected, 20*time.Millisecond)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("collectAll() took %v", elapsed)
+	}
+	if len(results) != len(selected) {
+		t.Fatalf("got %d results", len(results))
+	}
+	for i, r := range results {
+		if r.module.name != selected[i].name {
+			t.Errorf("result %d is %s, want %s", i, r.module.name, selected[i].name)
+		}
+	}
+	if results[0].value != "quick" || results[0].timedOut {
+		t.Errorf("fast = %+v", results[0])
+	}
+	if !results[1].timedOut || !results[2].timedOut {
+		t.Errorf("slow and stuck did not time out: %+v %+v", results[1], results[2])
+	}
+	if results[3].err == nil || results[3].timedOut {
+		t.Errorf("broken = %+v", results[3])
+	}
+
+	var out bytes.Buffer
+	colorEnabled = false
+	if err := printInfo(&out, results, nil, themes["none"]); err != nil {
+		t.Fatal(err)
+	}
+	want := "fast     quick\nslow     n/a\nstuck    n/a\n"
+	if out.String() != want {
+		t.Errorf("printInfo() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestCollectAllDeadline(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	results := collectAll(ctx, []module{{"stuck", "stuck", stuck}, {"slow", "slow", slow}}, time.Hour)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("collectAll() took %v", elapsed)
+	}
+	if !results[0].timedOut || !results[1].timedOut {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestPrintBench(t *testing.T) {
+	results := []result{
+		{module: module{name: "title"}, duration: 1500 * time.Microsecond},
+		{module: module{name: "packages"}, duration: time.Second, timedOut: true},
+	}
+	var out bytes.Buffer
+	if err := printBench(&out, results, time.Second+2*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	want := "title     1.5ms\npackages  1000.0ms  timeout\ntotal     1002.0ms\n"
+	if out.String() != want {
+		t.Errorf("printBench() = %q, want %q", out.String(), want)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		want    config
+		err     string
+	}{
+		{
+			name:    "empty file keeps the defaults",
+			content: "# nothing\n\n",
+			want:    config{Modules: defaultModules, Art: defaultArt, Theme: defaultTheme, Timeout: defaultTimeout, Deadline: defaultDeadline},
+		},
+		{
+			name: "all keys",
+			content: `# modules in the order they are shown
+modules = ip, title,uptime
+art = cat
+theme = sunset
+art_color = red
+title_color = bold #ff9966
+label_color = blue
+value_color = 2
+timeout = 250ms
+  deadline=3s  `,
+			want: config{
+				Modules:  []string{"ip", "title", "uptime"},
+				Art:      "cat",
+				Theme:    "sunset",
+				Colors:   theme{Art: "red", Title: "bold #ff9966", Label: "blue", Value: "2"},
+				Timeout:  250 * time.Millisecond,
+				Deadline: 3 * time.Second,
+			},
+		},
+		{name: "unknown key", content: "modules = title\ncolour = red\n", err: `config:2: unknown key "colour"`},
+		{name: "missing value", content: "modules\n", err: "config:1: expected key = value"},
+		{name: "invalid duration", content: "timeout = soon\n", err: `config:1: invalid timeout "soon"`},
+		{name: "negative duration", content: "deadline = -1s\n", err: `config:1: invalid deadline "-1s"`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config")
+			if err := os.WriteFile(path, []byte(tt.content), 0o644); err != nil {
+				t.Fatal(err)
+			}
+			got, err := loadConfig(path, true)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("loadConfig() error = %v, want %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if strings.Join(got.Modules, ",") != strings.Join(tt.want.Modules, ",") || got.Art != tt.want.Art || got.Theme != tt.want.Theme ||
+				got.Colors != tt.want.Colors || got.Timeout != tt.want.Timeout || got.Deadline != tt.want.Deadline {
+				t.Errorf("loadConfig() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+
+	// a missing default config is fine, a missing --config is not
+	missing := filepath.Join(t.TempDir(), "missing")
+	if cfg, err := loadConfig(missing, false); err != nil || cfg.Art != defaultArt {
+		t.Errorf("loadConfig(missing, false) = %+v, %v", cfg, err)
+	}
+	if _, err := loadConfig(missing, true); !errors.Is(err, os.ErrNotExist) {
+		t.Errorf("loadConfig(missing, true) error = %v", err)
+	}
+}
+
+func TestOverride(t *testing.T) {
+	cfg := config{Modules: []string{"title", "uptime"}, Art: "cat", Theme: "sunset", Timeout: time.Second, Deadline: 2 * time.Second}
+
+	// without flags the config wins
+	if got := cfg.override("", "", "", 0, 0); strings.Join(got.Modules, ",") != "title,uptime" || got.Art != "cat" || got.Theme != "sunset" || got.Timeout != time.Second {
+		t.Errorf("override() without flags = %+v", got)
+	}
+
+	got := cfg.override("memory, title", "bunny", "default", 100*time.Millisecond, 5*time.Second)
+	if strings.Join(got.Modules, ",") != "memory,title" || got.Art != "bunny" || got.Theme != "default" ||
+		got.Timeout != 100*time.Millisecond || got.Deadline != 5*time.Second {
+		t.Errorf("override() = %+v", got)
+	}
+	if strings.Join(cfg.Modules, ",") != "title,uptime" {
+		t.Errorf("override() changed the config: %+v", cfg)
+	}
+}
+
+func TestSelectModules(t *testing.T) {
+	tests := []struct {
+		names []string
+		want  string
+		err   string
+	}{
+		{names: defaultModules, want: "title,distro,kernel,uptime,shell,cpu,memory"},
+		{names: []string{"ip", "title", "os"}, want: "ip,title,os"},
+		{names: []string{}, want: ""},
+		{names: []string{"title", "weather"}, err: `unknown module "weather", available: title, distro`},
+		{names: []string{"Title"}, err: `unknown module "Title"`},
+		{names: []string{"uptime", "title", "uptime"}, err: `module "uptime" is listed more than once`},
+	}
+	for _, tt := range tests {
+		selected, err := selectModules(tt.names)
+		if tt.err != "" {
+			if err == nil || !strings.Contains(err.Error(), tt.err) {
+				t.Errorf("selectModules(%v) error = %v, want %q", tt.names, err, tt.err)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("selectModules(%v): %v", tt.names, err)
+			continue
+		}
+		names := make([]string, len(selected))
+		for i, m := range selected {
+			names[i] = m.name
+		}
+		if got := strings.Join(names, ","); got != tt.want {
+			t.Errorf("selectModules(%v) = %s, want %s", tt.names, got, tt.want)
+		}
+	}
+}
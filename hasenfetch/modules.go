@@ -0,0 +1,421 @@
+package main
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mackerelio/go-osstat/loadavg"
+	"github.com/mackerelio/go-osstat/memory"
+)
+
+// module is a single piece of information about the system
+type module struct {
+	name  string
+	label string
+	// collect returns the value of the module, it is printed with fmt and encoded as JSON.
+	// A value that prints empty is hidden. Collecting is abandoned when ctx is done.
+	collect func(ctx context.Context) (any, error)
+}
+
+// modules are all available modules, in their default order
+var modules = []module{
+	{"title", "", getTitle},
+	{"distro", "distro", getDistro},
+	{"os", "os", func(context.Context) (any, error) { return runtime.GOOS, nil }},
+	{"arch", "arch", func(context.Context) (any, error) { return runtime.GOARCH, nil }},
+	{"kernel", "kernel", getKernel},
+	{"uptime", "uptime", getUptime},
+	{"shell", "shell", getShell},
+	{"terminal", "terminal", getTerminal},
+	{"cpu", "cpu", getCPU},
+	{"memory", "memory", getMemory},
+	{"disk", "disk", getDisk},
+	{"load", "load", getLoad},
+	{"packages", "packages", getPackages},
+	{"ip", "ip", getLocalIPs},
+}
+
+// defaultModules are shown without a config file or --modules
+var defaultModules = []string{"title", "distro", "kernel", "uptime", "shell", "cpu", "memory"}
+
+// selectModules returns the modules with the given names in the given order
+func selectModules(names []string) ([]module, error) {
+	selected := make([]module, 0, len(names))
+	seen := map[string]bool{}
+	for _, name := range names {
+		if seen[name] {
+			return nil, fmt.Errorf("module %q is listed more than once", name)
+		}
+		seen[name] = true
+		found := false
+		for _, m := range modules {
+			if m.name == name {
+				selected = append(selected, m)
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("unknown module %q, available: %s", name, strings.Join(moduleNames(), ", "))
+		}
+	}
+	return selected, nil
+}
+
+func moduleNames() []string {
+	names := make([]string, len(modules))
+	for i, m := range modules {
+		names[i] = m.name
+	}
+	return names
+}
+
+type title struct {
+	User string `json:"user"`
+	Host string `json:"host"`
+}
+
+func (t title) String() string {
+	return t.User + "@" + t.Host
+}
+
+func getTitle(context.Context) (any, error) {
+	currentUser, err := user.Current()
+	if err != nil {
+		return nil, err
+	}
+	hostname, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	return title{User: currentUser.Username, Host: hostname}, nil
+}
+
+// getDistro reads the name of the distribution from os-release, other systems get their GOOS
+func getDistro(context.Context) (any, error) {
+	release := readOSRelease()
+	if name := release["PRETTY_NAME"]; name != "" {
+		return name, nil
+	}
+	if name := strings.TrimSpace(release["NAME"] + " " + release["VERSION"]); name != "" {
+		return name, nil
+	}
+	return runtime.GOOS, nil
+}
+
+// readOSRelease reads os-release, it is empty on systems without one
+func readOSRelease() map[string]string {
+	for _, path := range []string{"/etc/os-release", "/usr/lib/os-release"} {
+		f, err := os.Open(path)
+		if err != nil {
+			continue
+		}
+		defer f.Close()
+		return parseOSRelease(f)
+	}
+	return map[string]string{}
+}
+
+// parseOSRelease reads the KEY=value lines of an os-release file
+func parseOSRelease(r io.Reader) map[string]string {
+	release := map[string]string{}
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(strings.TrimSpace(scanner.Text()), "=")
+		if !ok || strings.HasPrefix(key, "#") {
+			continue
+		}
+		if unquoted, err := strconv.Unquote(value); err == nil {
+			value = unquoted
+		} else {
+			value = strings.Trim(value, `"'`)
+		}
+		release[key] = value
+	}
+	return release
+}
+
+func getKernel(ctx context.Context) (any, error) {
+	if data, err := os.ReadFile("/proc/sys/kernel/osrelease"); err == nil {
+		return "Linux " + strings.TrimSpace(string(data)), nil
+	}
+	out, err := exec.CommandContext(ctx, "uname", "-sr").Output()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get kernel version: %w", err)
+	}
+	return strings.TrimSpace(string(out)), nil
+}
+
+// uptimeInfo is the time since boot
+type uptimeInfo time.Duration
+
+func (u uptimeInfo) String() string {
+	return formatUptime(time.Duration(u))
+}
+
+func (u uptimeInfo) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Seconds int64  `json:"seconds"`
+		Text    string `json:"text"`
+	}{int64(time.Duration(u) / time.Second), u.String()})
+}
+
+func getUptime(context.Context) (any, error) {
+	uptime, err := getSystemUptime()
+	if err != nil {
+		return nil, err
+	}
+	return uptimeInfo(uptime), nil
+}
+
+// formatUptime renders d with its largest unit and the next one, like "3 days, 1 hour" or "5 minutes, 12 seconds"
+func formatUptime(d time.Duration) string {
+	units := []struct {
+		name string
+		size time.Duration
+	}{
+		{"day", 24 * time.Hour},
+		{"hour", time.Hour},
+		{"minute", time.Minute},
+		{"second", time.Second},
+	}
+	for i, unit := range units {
+		n := int64(d / unit.size)
+		if n == 0 && unit.size != time.Second {
+			continue
+		}
+		parts := []string{plural(n, unit.name)}
+		if i+1 < len(units) {
+			next := units[i+1]
+			if m := int64(d % unit.size / next.size); m > 0 {
+				parts = append(parts, plural(m, next.name))
+			}
+		}
+		return strings.Join(parts, ", ")
+	}
+	return ""
+}
+
+func plural(n int64, unit string) string {
+	if n == 1 {
+		return "1 " + unit
+	}
+	return fmt.Sprintf("%d %ss", n, unit)
+}
+
+func getShell(context.Context) (any, error) {
+	shell := os.Getenv("SHELL")
+	if shell == "" {
+		return "", nil
+	}
+	return filepath.Base(shell), nil
+}
+
+func getTerminal(context.Context) (any, error) {
+	if term := os.Getenv("TERM_PROGRAM"); term != "" {
+		return term, nil
+	}
+	return os.Getenv("TERM"), nil
+}
+
+type cpuInfo struct {
+	Model string `json:"model"`
+	Cores int    `json:"cores"`
+}
+
+func (c cpuInfo) String() string {
+	return fmt.Sprintf("%s (%d)", c.Model, c.Cores)
+}
+
+// getCPU returns the model and the number of logical cores
+func getCPU(ctx context.Context) (any, error) {
+	model := ""
+	if f, err := os.Open("/proc/cpuinfo"); err == nil {
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			key, value, ok := strings.Cut(scanner.Text(), ":")
+			if ok && strings.TrimSpace(key) == "model name" {
+				model = strings.TrimSpace(value)
+				break
+			}
+		}
+		f.Close()
+	} else if runtime.GOOS == "darwin" {
+		out, err := exec.CommandContext(ctx, "sysctl", "-n", "machdep.cpu.brand_string").Output()
+		if err == nil {
+			model = strings.TrimSpace(string(out))
+		}
+	}
+	if model == "" {
+		model = runtime.GOARCH
+	}
+	return cpuInfo{Model: model, Cores: runtime.NumCPU()}, nil
+}
+
+// usage is the used and total bytes of memory or a file system
+type usage struct {
+	Path  string `json:"path,omitempty"`
+	Used  uint64 `json:"used"`
+	Total uint64 `json:"total"`
+}
+
+// String formats the usage like "3.1 GiB / 15.5 GiB (20%)"
+func (u usage) String() string {
+	percent := 0.0
+	if u.Total > 0 {
+		percent = float64(u.Used) / float64(u.Total) * 100
+	}
+	s := fmt.Sprintf("%s / %s (%.0f%%)", formatBytes(u.Used), formatBytes(u.Total), percent)
+	if u.Path != "" {
+		s += " " + u.Path
+	}
+	return s
+}
+
+func getMemory(context.Context) (any, error) {
+	stats, err := memory.Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get memory usage: %w", err)
+	}
+	return usage{Used: stats.Used, Total: stats.Total}, nil
+}
+
+func getDisk(context.Context) (any, error) {
+	used, total, err := diskUsage("/")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get disk usage: %w", err)
+	}
+	return usage{Path: "/", Used: used, Total: total}, nil
+}
+
+type load struct {
+	One     float64 `json:"1m"`
+	Five    float64 `json:"5m"`
+	Fifteen float64 `json:"15m"`
+}
+
+func (l load) String() string {
+	return fmt.Sprintf("%.2f %.2f %.2f", l.One, l.Five, l.Fifteen)
+}
+
+func getLoad(context.Context) (any, error) {
+	stats, err := loadavg.Get()
+	if err != nil {
+		return nil, fmt.Errorf("failed to get load average: %w", err)
+	}
+	return load{One: stats.Loadavg1, Five: stats.Loadavg5, Fifteen: stats.Loadavg15}, nil
+}
+
+// packageManagers list the installed packages one per line
+var packageManagers = []struct {
+	name string
+	args []string
+}{
+	{"dpkg-query", []string{"-f", ".\n", "-W"}},
+	{"pacman", []string{"-Qq"}},
+	{"rpm", []string{"-qa"}},
+	{"apk", []string{"info"}},
+	{"xbps-query", []string{"-l"}},
+	{"brew", []string{"list", "-1"}},
+	{"pkg", []string{"info", "-q"}},
+	{"flatpak", []string{"list", "--app", "--columns=application"}},
+	{"snap", []string{"list"}},
+}
+
+type packageCount struct {
+	Manager string `json:"manager"`
+	Count   int    `json:"count"`
+}
+
+type packageCounts []packageCount
+
+func (p packageCounts) String() string {
+	counts := make([]string, len(p))
+	for i, c := range p {
+		counts[i] = fmt.Sprintf("%d (%s)", c.Count, c.Manager)
+	}
+	return strings.Join(counts, ", ")
+}
+
+// getPackages counts the packages of every package manager that is installed
+func getPackages(ctx context.Context) (any, error) {
+	counts := packageCounts{}
+	for _, pm := range packageManagers {
+		if _, err := exec.LookPath(pm.name); err != nil {
+			continue
+		}
+		out, err := exec.CommandContext(ctx, pm.name, pm.args...).Output()
+		if err != nil {
+			continue
+		}
+		n := strings.Count(string(out), "\n")
+		if pm.name == "snap" && n > 0 {
+			// header line
+			n--
+		}
+		if n > 0 {
+			counts = append(counts, packageCount{Manager: strings.TrimSuffix(pm.name, "-query"), Count: n})
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	return counts, nil
+}
+
+type addresses []string
+
+func (a addresses) String() string {
+	return strings.Join(a, ", ")
+}
+
+// getLocalIPs returns the addresses of the interfaces that are up, without loopback and link-local addresses
+func getLocalIPs(context.Context) (any, error) {
+	interfaces, err := net.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+	ips := addresses{}
+	for _, iface := range interfaces {
+		if iface.Flags&net.FlagUp == 0 || iface.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		addrs, err := iface.Addrs()
+		if err != nil {
+			continue
+		}
+		for _, addr := range addrs {
+			ipNet, ok := addr.(*net.IPNet)
+			if !ok || ipNet.IP.IsLinkLocalUnicast() {
+				continue
+			}
+			ips = append(ips, ipNet.IP.String())
+		}
+	}
+	return ips, nil
+}
+
+func formatBytes(b uint64) string {
+	const unit = 1024
+	if b < unit {
+		return fmt.Sprintf("%d B", b)
+	}
+	div, exp := uint64(unit), 0
+	for n := b / unit; n >= unit; n /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %ciB", float64(b)/float64(div), "KMGTPE"[exp])
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// theme holds the colors of the parts of the output.
+// A color is a space separated list of attributes (bold, dim, italic, underline),
+// basic colors (red, bright-red, ...), 256 color numbers (0-255) and truecolor hex codes (#rrggbb).
+type theme struct {
+	Art   string
+	Title string
+	Label string
+	Value string
+}
+
+// themes are the built-in themes
+var themes = map[string]theme{
+	"default": {Art: "magenta", Title: "bold", Label: "cyan"},
+	"mono":    {Title: "bold", Label: "bold"},
+	"none":    {},
+	"pastel":  {Art: "218", Title: "bold 183", Label: "152", Value: "255"},
+	"sunset":  {Art: "#ff7e5f", Title: "bold #feb47b", Label: "#ff9966"},
+	"forest":  {Art: "#7fb069", Title: "bold #e6aa68", Label: "#9bc53d"},
+}
+
+// defaultTheme is the theme without a config file or --theme
+const defaultTheme = "default"
+
+func themeNames() []string {
+	names := make([]string, 0, len(themes))
+	for name := range themes {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// validate reports the first color of the theme that cannot be parsed
+func (t theme) validate() error {
+	for _, c := range []string{t.Art, t.Title, t.Label, t.Value} {
+		if _, err := sgr(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+var basicColors = []string{"black", "red", "green", "yellow", "blue", "magenta", "cyan", "white"}
+
+var attributes = map[string]string{
+	"bold":      "1",
+	"dim":       "2",
+	"italic":    "3",
+	"underline": "4",
+}
+
+// sgr returns the parameters of the ANSI escape sequence for a color like "bold #ff8800"
+func sgr(color string) (string, error) {
+	var params []string
+	for _, field := range strings.Fields(color) {
+		if code, ok := attributes[field]; ok {
+			params = append(params, code)
+			continue
+		}
+		if code, ok := basicColor(field); ok {
+			params = append(params, code)
+			continue
+		}
+		if hex, ok := strings.CutPrefix(field, "#"); ok {
+			v, err := strconv.ParseUint(hex, 16, 32)
+			if err != nil || len(hex) != 6 {
+				return "", fmt.Errorf("invalid color %q, expected #rrggbb", field)
+			}
+			params = append(params, fmt.Sprintf("38;2;%d;%d;%d", v>>16, v>>8&0xff, v&0xff))
+			continue
+		}
+		n, err := strconv.ParseUint(field, 10, 8)
+		if err != nil {
+			return "", fmt.Errorf("invalid color %q, expected a name, 0-255 or #rrggbb", field)
+		}
+		params = append(params, fmt.Sprintf("38;5;%d", n))
+	}
+	return strings.Join(params, ";"), nil
+}
+
+func basicColor(name string) (string, bool) {
+	base, bright := strings.CutPrefix(name, "bright-")
+	for i, c := range basicColors {
+		if c == base {
+			if bright {
+				return strconv.Itoa(90 + i), true
+			}
+			return strconv.Itoa(30 + i), true
+		}
+	}
+	return "", false
+}
+
+// colorEnabled is set when the output gets colors
+var colorEnabled bool
+
+// colorize wraps s in the escape sequences of color, colors are validated up front
+func colorize(color, s string) string {
+	params, _ := sgr(color)
+	if !colorEnabled || params == "" || s == "" {
+		return s
+	}
+	return "\x1b[" + params + "m" + s + "\x1b[0m"
+}
+
+// useColor decides on colors for the --color mode, auto disables them for NO_COLOR and when stdout is no terminal
+func useColor(mode string) (bool, error) {
+	switch mode {
+	case "always":
+		return true, nil
+	case "never":
+		return false, nil
+	case "auto":
+		if os.Getenv("NO_COLOR") != "" {
+			return false, nil
+		}
+		info, err := os.Stdout.Stat()
+		return err == nil && info.Mode()&os.ModeCharDevice != 0, nil
+	}
+	return false, fmt.Errorf("invalid color mode %q, expected auto, always or never", mode)
+}
@@ -0,0 +1,153 @@
+package battery
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// fetchArchive downloads a .zip, .tar.gz or .tar archive, verifies its checksum and
+// vendors the selected files. A single top-level folder, as in release archives, is stripped.
+func (b Battery) fetchArchive(dst string, pin Pin, opts Options) (Pin, error) {
+	data, err := b.fetchCached("archives", pin, opts)
+	if err != nil {
+		return Pin{}, err
+	}
+	files, err := readArchive(data)
+	if err != nil {
+		return Pin{}, fmt.Errorf("battery %s: %w", b.Name, err)
+	}
+	if err := b.vendor(dst, stripTopLevel(files)); err != nil {
+		return Pin{}, err
+	}
+	return Pin{SHA256: checksum(data)}, nil
+}
+
+// readArchive returns the regular files of an archive by their slash separated path, the format is detected from the content
+func readArchive(data []byte) (map[string][]byte, error) {
+	switch {
+	case bytes.HasPrefix(data, []byte("PK\x03\x04")):
+		return readZip(data)
+	case bytes.HasPrefix(data, []byte{0x1f, 0x8b}):
+		gz, err := gzip.NewReader(bytes.NewReader(data))
+		if err != nil {
+			return nil, err
+		}
+		defer gz.Close()
+		return readTar(gz)
+	}
+	return readTar(bytes.NewReader(data))
+}
+
+func readZip(data []byte) (map[string][]byte, error) {
+	r, err := zip.NewReader(bytes.NewReader(data), int64(len(data)))
+	if err != nil {
+		return nil, err
+	}
+	files := map[string][]byte{}
+	for _, f := range r.File {
+		if !f.Mode().IsRegular() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		content, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(f.Name)] = content
+	}
+	return files, nil
+}
+
+func readTar(r io.Reader) (map[string][]byte, error) {
+	files := map[string][]byte{}
+	tr := tar.NewReader(r)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, fmt.Errorf("not a zip or tar archive: %w", err)
+		}
+		if hdr.Typeflag != tar.TypeReg {
+			continue
+		}
+		content, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		files[path.Clean(hdr.Name)] = content
+	}
+}
+
+// stripTopLevel removes a folder that contains all files
+func stripTopLevel(files map[string][]byte) map[string][]byte {
+	top := ""
+	for name := range files {
+		dir, _, ok := strings.Cut(name, "/")
+		if !ok || (top != "" && dir != top) {
+			return files
+		}
+		top = dir
+	}
+	stripped := make(map[string][]byte, len(files))
+	for name, content := range files {
+		stripped[strings.TrimPrefix(name, top+"/")] = content
+	}
+	return stripped
+}
+
+// vendor writes the files selected by Subdir and Files to dst, files are keyed by their slash separated path in the source
+func (b Battery) vendor(dst string, files map[string][]byte) error {
+	prefix := ""
+	if b.Subdir != "" {
+		prefix = path.Clean(b.Subdir) + "/"
+	}
+	selected := map[string][]byte{}
+	for name, content := range files {
+		if rel, ok := strings.CutPrefix(name, prefix); ok {
+			selected[rel] = content
+		}
+	}
+	if len(selected) == 0 {
+		return fmt.Errorf("battery %s: subdir %s: no files", b.Name, b.Subdir)
+	}
+
+	if len(b.Files) > 0 {
+		only := map[string][]byte{}
+		for _, name := range b.Files {
+			content, ok := selected[path.Clean(name)]
+			if !ok {
+				return fmt.Errorf("battery %s: %s: file not found", b.Name, name)
+			}
+			only[path.Clean(name)] = content
+		}
+		selected = only
+	}
+
+	for name, content := range selected {
+		if !filepath.IsLocal(filepath.FromSlash(name)) || strings.Contains(name, "\\") {
+			return fmt.Errorf("refusing to write %s outside of the battery", name)
+		}
+		target := filepath.Join(dst, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(target, content, 0o644); err != nil {
+			return fmt.Errorf("failed to vendor %s: %w", b.Name, err)
+		}
+	}
+	return nil
+}
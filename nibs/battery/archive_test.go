@@ -0,0 +1,112 @@
+package battery
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var archiveFiles = map[string]string{
+	"knife-1.0/README.md":      "knife",
+	"knife-1.0/knife/base.lua": "return 'base'",
+	"knife-1.0/knife/test.lua": "return 'test'",
+}
+
+func tarGz(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0o644, Size: int64(len(content)), Typeflag: tar.TypeReg}); err != nil {
+			t.Fatal(err)
+		}
+		tw.Write([]byte(content))
+	}
+	tw.Close()
+	gz.Close()
+	return buf.Bytes()
+}
+
+func zipArchive(t *testing.T, files map[string]string) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for name, content := range files {
+		w, err := zw.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		w.Write([]byte(content))
+	}
+	zw.Close()
+	return buf.Bytes()
+}
+
+func TestInstallArchive(t *testing.T) {
+	archives := map[string][]byte{
+		"/knife.tar.gz": tarGz(t, archiveFiles),
+		"/knife.zip":    zipArchive(t, archiveFiles),
+	}
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		data, ok := archives[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		w.Write(data)
+	}))
+	defer srv.Close()
+
+	for name, data := range archives {
+		t.Run(name, func(t *testing.T) {
+			dir := t.TempDir()
+			opts := Options{CacheDir: t.TempDir()}
+			b := Battery{Name: "knife", Source: SourceArchive, URL: srv.URL + name, SHA256: checksum(data), Subdir: "knife", Target: "lib/knife"}
+
+			pin, err := b.Install(dir, Pin{}, opts)
+			if err != nil {
+				t.Fatalf("Install: %v", err)
+			}
+			if pin.SHA256 != checksum(data) || len(pin.Files) != 2 {
+				t.Errorf("unexpected pin %+v", pin)
+			}
+			if got := readVendored(t, dir, "lib/knife/base.lua"); got != "return 'base'" {
+				t.Errorf("base.lua = %q", got)
+			}
+
+			// selecting single files
+			b.Files = []string{"test.lua"}
+			b.Target = "test"
+			if _, err := b.Install(dir, Pin{}, opts); err != nil {
+				t.Fatalf("Install with files: %v", err)
+			}
+			if got := readVendored(t, dir, "test/test.lua"); got != "return 'test'" {
+				t.Errorf("test.lua = %q", got)
+			}
+
+			// the archive does not match the expected checksum
+			b.SHA256 = strings.Repeat("0", 64)
+			b.Target = "other"
+			if _, err := b.Install(dir, Pin{}, opts); err == nil || !strings.Contains(err.Error(), "checksum mismatch") {
+				t.Errorf("expected a checksum mismatch, got %v", err)
+			}
+		})
+	}
+}
+
+func TestStripTopLevel(t *testing.T) {
+	files := stripTopLevel(map[string][]byte{"a/x.lua": nil, "a/b/y.lua": nil})
+	if _, ok := files["b/y.lua"]; !ok || len(files) != 2 {
+		t.Errorf("stripped %v", files)
+	}
+	files = stripTopLevel(map[string][]byte{"a/x.lua": nil, "y.lua": nil})
+	if _, ok := files["a/x.lua"]; !ok {
+		t.Errorf("files without a common folder must stay as they are: %v", files)
+	}
+}
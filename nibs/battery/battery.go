@@ -0,0 +1,179 @@
+// Package battery vendors third party libraries ("batteries") into LÖVE projects.
+package battery
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Source types a battery can be fetched from
+const (
+	SourceGit     = "git"
+	SourceFile    = "file"
+	SourceArchive = "archive"
+	SourceLocal   = "local"
+)
+
+// Battery describes where a library comes from and where it ends up in the project
+type Battery struct {
+	Name string `toml:"-"`
+	// Source is "git", "file", "archive" or "local"
+	Source string `toml:"source"`
+	// URL of the git repository, the raw file or the .zip, .tar.gz or .tar archive
+	URL string `toml:"url,omitempty"`
+	// Path of a local folder or file, relative to the project root, for local sources
+	Path string `toml:"path,omitempty"`
+	// Ref is a branch, tag or commit for git sources
+	Ref string `toml:"ref,omitempty"`
+	// SHA256 is the expected checksum of a downloaded file or archive
+	SHA256 string `toml:"sha256,omitempty"`
+	// Subdir selects a directory inside of a git repository, archive or local folder to vendor
+	Subdir string `toml:"subdir,omitempty"`
+	// Files selects single files (relative to Subdir) to vendor
+	Files []string `toml:"files,omitempty"`
+	// Target is the path relative to the source folder of the project, where Lua can require it
+	Target string `toml:"target"`
+}
+
+// Pin records the exact version of a vendored battery
+type Pin struct {
+	Commit string `toml:"commit,omitempty"`
+	// SHA256 is the checksum of a downloaded file or archive, or of all files of a local source
+	SHA256 string `toml:"sha256,omitempty"`
+	// Files maps the vendored files (relative to the source folder) to their sha256
+	Files map[string]string `toml:"files,omitempty"`
+}
+
+// Validate checks that the battery definition is complete
+func (b Battery) Validate() error {
+	if b.Source == SourceLocal {
+		if b.Path == "" {
+			return fmt.Errorf("battery %s: missing path", b.Name)
+		}
+	} else if b.URL == "" {
+		return fmt.Errorf("battery %s: missing url", b.Name)
+	}
+	if b.Target == "" {
+		return fmt.Errorf("battery %s: missing target", b.Name)
+	}
+	if filepath.IsAbs(b.Target) || !filepath.IsLocal(b.Target) {
+		return fmt.Errorf("battery %s: target %q must be inside the project", b.Name, b.Target)
+	}
+	switch b.Source {
+	case SourceGit, SourceFile, SourceArchive, SourceLocal:
+	default:
+		return fmt.Errorf("battery %s: unknown source %q", b.Name, b.Source)
+	}
+	return nil
+}
+
+// rename moves the staged files into the project, tests replace it to simulate failures
+var rename = os.Rename
+
+// ErrExists is returned when the target of a battery already contains different files
+var ErrExists = errors.New("target already exists")
+
+// Install fetches the battery and vendors it into projectDir, the source folder of the project.
+// If pin is not empty exactly that version is installed, otherwise the battery's ref is resolved.
+// The battery is staged in a temporary directory first and only moved into place once it was fetched completely.
+// Installing the same version twice is a no-op, existing files that differ are only replaced with opts.Force.
+// The returned pin describes what was installed.
+func (b Battery) Install(projectDir string, pin Pin, opts Options) (Pin, error) {
+	if err := b.Validate(); err != nil {
+		return Pin{}, err
+	}
+	target := filepath.Join(projectDir, b.Target)
+
+	_, statErr := os.Stat(target)
+	exists := statErr == nil
+	// unchanged vendored files of a local battery are replaced when its source changed
+	clean := false
+	if exists && len(pin.Files) > 0 {
+		changes, err := b.LocalChanges(projectDir, pin)
+		if err != nil {
+			return Pin{}, err
+		}
+		if changes.Empty() {
+			// a local battery has no version to pin, it is only installed while its source is unchanged
+			if b.Source != SourceLocal {
+				return pin, nil
+			}
+			sum, err := b.localChecksum(projectDir, opts)
+			if err != nil {
+				return Pin{}, err
+			}
+			if sum == pin.SHA256 {
+				return pin, nil
+			}
+			clean = true
+		}
+	}
+
+	// stage inside of the project so the final rename stays on the same file system
+	stage, err := os.MkdirTemp(projectDir, ".nibs-stage-")
+	if err != nil {
+		return Pin{}, err
+	}
+	defer os.RemoveAll(stage)
+
+	staged := filepath.Join(stage, b.Target)
+	switch b.Source {
+	case SourceGit:
+		pin, err = b.fetchGit(staged, pin, opts)
+	case SourceFile:
+		pin, err = b.fetchFile(staged, pin, opts)
+	case SourceArchive:
+		pin, err = b.fetchArchive(staged, pin, opts)
+	case SourceLocal:
+		pin, err = b.fetchLocal(staged, projectDir, opts)
+	}
+	if err != nil {
+		return Pin{}, err
+	}
+	pin.Files, err = Checksums(stage, b.Target)
+	if err != nil {
+		return Pin{}, err
+	}
+
+	if exists {
+		current, err := Checksums(projectDir, b.Target)
+		if err != nil {
+			return Pin{}, err
+		}
+		if Diff(current, pin.Files).Empty() {
+			return pin, nil
+		}
+		if !opts.Force && !clean {
+			return Pin{}, fmt.Errorf("battery %s: %s: %w, use --force to overwrite", b.Name, b.Target, ErrExists)
+		}
+		// move the old version out of the way, it is deleted together with the stage once the new one is in place
+		old := filepath.Join(stage, ".old")
+		if err := rename(target, old); err != nil {
+			return Pin{}, err
+		}
+		if err := rename(staged, target); err != nil {
+			if restoreErr := rename(old, target); restoreErr != nil {
+				return Pin{}, fmt.Errorf("battery %s: %w, the previous version could not be restored: %v", b.Name, err, restoreErr)
+			}
+			return Pin{}, err
+		}
+		return pin, nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return Pin{}, err
+	}
+	if err := rename(staged, target); err != nil {
+		return Pin{}, err
+	}
+	return pin, nil
+}
+
+// Remove deletes the vendored files of the battery from projectDir
+func (b Battery) Remove(projectDir string) error {
+	if err := b.Validate(); err != nil {
+		return err
+	}
+	return os.RemoveAll(filepath.Join(projectDir, b.Target))
+}
@@ -0,0 +1,49 @@
+package battery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"os"
+	"path/filepath"
+)
+
+// Options control how batteries are fetched and installed
+type Options struct {
+	// CacheDir holds cached repositories and downloads, DefaultCacheDir is used if empty
+	CacheDir string
+	// Offline only uses the cache and never touches the network
+	Offline bool
+	// Force overwrites existing files at the target
+	Force bool
+	// BaseDir is where relative paths of local batteries start, the project directory if empty
+	BaseDir string
+}
+
+// DefaultCacheDir returns $NIBS_CACHE_DIR or the nibs folder inside of the user cache dir
+func DefaultCacheDir() (string, error) {
+	if dir := os.Getenv("NIBS_CACHE_DIR"); dir != "" {
+		return dir, nil
+	}
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "nibs"), nil
+}
+
+func (o Options) cacheDir() (string, error) {
+	if o.CacheDir != "" {
+		return o.CacheDir, nil
+	}
+	return DefaultCacheDir()
+}
+
+// cachePath returns the location inside of the cache for the given kind of source and url
+func (o Options) cachePath(kind, url string) (string, error) {
+	dir, err := o.cacheDir()
+	if err != nil {
+		return "", err
+	}
+	sum := sha256.Sum256([]byte(url))
+	return filepath.Join(dir, kind, hex.EncodeToString(sum[:16])), nil
+}
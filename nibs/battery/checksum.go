@@ -0,0 +1,98 @@
+package battery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// Checksums returns the sha256 of every file below target, keyed by the slash separated path relative to projectDir.
+// A missing target results in an empty map.
+func Checksums(projectDir, target string) (map[string]string, error) {
+	sums := map[string]string{}
+	root := filepath.Join(projectDir, target)
+	err := filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(projectDir, path)
+		if err != nil {
+			return err
+		}
+		sum, err := fileChecksum(path)
+		if err != nil {
+			return err
+		}
+		sums[filepath.ToSlash(rel)] = sum
+		return nil
+	})
+	if errors.Is(err, os.ErrNotExist) {
+		return sums, nil
+	}
+	return sums, err
+}
+
+func fileChecksum(path string) (string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+	defer f.Close()
+
+	h := sha256.New()
+	if _, err := io.Copy(h, f); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(h.Sum(nil)), nil
+}
+
+// Changes lists the files that differ between two sets of checksums
+type Changes struct {
+	Added    []string
+	Removed  []string
+	Modified []string
+}
+
+// Empty reports whether there are no changes at all
+func (c Changes) Empty() bool {
+	return len(c.Added) == 0 && len(c.Removed) == 0 && len(c.Modified) == 0
+}
+
+// Diff compares the checksums in from with the ones in to
+func Diff(from, to map[string]string) Changes {
+	var c Changes
+	for path, sum := range to {
+		old, ok := from[path]
+		switch {
+		case !ok:
+			c.Added = append(c.Added, path)
+		case old != sum:
+			c.Modified = append(c.Modified, path)
+		}
+	}
+	for path := range from {
+		if _, ok := to[path]; !ok {
+			c.Removed = append(c.Removed, path)
+		}
+	}
+	sort.Strings(c.Added)
+	sort.Strings(c.Removed)
+	sort.Strings(c.Modified)
+	return c
+}
+
+// LocalChanges compares the files in projectDir with the checksums recorded when the battery was vendored
+func (b Battery) LocalChanges(projectDir string, pin Pin) (Changes, error) {
+	sums, err := Checksums(projectDir, b.Target)
+	if err != nil {
+		return Changes{}, err
+	}
+	return Diff(pin.Files, sums), nil
+}
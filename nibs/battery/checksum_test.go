@@ -0,0 +1,68 @@
+package battery
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestDiff(t *testing.T) {
+	from := map[string]string{"a.lua": "1", "b.lua": "2", "c.lua": "3"}
+	to := map[string]string{"a.lua": "1", "b.lua": "changed", "d.lua": "4"}
+
+	got := Diff(from, to)
+	want := Changes{
+		Added:    []string{"d.lua"},
+		Removed:  []string{"c.lua"},
+		Modified: []string{"b.lua"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Diff() = %+v, want %+v", got, want)
+	}
+	if !Diff(from, from).Empty() {
+		t.Errorf("Diff of identical sets should be empty")
+	}
+}
+
+func TestLocalChanges(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "lib", "sub"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lib", "a.lua"), []byte("return 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, "lib", "sub", "b.lua"), []byte("return 2"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	b := Battery{Name: "lib", Source: SourceGit, URL: "unused", Target: "lib"}
+	sums, err := Checksums(dir, b.Target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := sums["lib/sub/b.lua"]; !ok || len(sums) != 2 {
+		t.Fatalf("unexpected checksums: %v", sums)
+	}
+	pin := Pin{Files: sums}
+
+	changes, err := b.LocalChanges(dir, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changes.Empty() {
+		t.Errorf("expected no local changes, got %+v", changes)
+	}
+
+	if err := os.WriteFile(filepath.Join(dir, "lib", "a.lua"), []byte("return 3"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	changes, err = b.LocalChanges(dir, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(changes.Modified, []string{"lib/a.lua"}) {
+		t.Errorf("expected lib/a.lua to be modified, got %+v", changes)
+	}
+}
@@ -0,0 +1,115 @@
+package battery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// fetchFile writes a single downloaded file to dst and verifies it against the pinned checksum
+func (b Battery) fetchFile(dst string, pin Pin, opts Options) (Pin, error) {
+	data, err := b.fetchCached("files", pin, opts)
+	if err != nil {
+		return Pin{}, err
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return Pin{}, err
+	}
+	if err := os.WriteFile(dst, data, 0o644); err != nil {
+		return Pin{}, fmt.Errorf("failed to write %s: %w", dst, err)
+	}
+	return Pin{SHA256: checksum(data)}, nil
+}
+
+// fetchCached downloads the url of the battery and verifies it against the pinned and the expected checksum.
+// Downloads are kept in the cache so they can be installed again without network access.
+func (b Battery) fetchCached(kind string, pin Pin, opts Options) ([]byte, error) {
+	cache, err := opts.cachePath(kind, b.URL)
+	if err != nil {
+		return nil, err
+	}
+	want := pin.SHA256
+	if want == "" {
+		want = b.SHA256
+	}
+
+	data, cacheErr := os.ReadFile(cache)
+	cached := cacheErr == nil && (want == "" || strings.EqualFold(checksum(data), want))
+
+	// a pinned file that is already cached never changes, so we can skip the network
+	if !opts.Offline && !(cached && want != "") {
+		downloaded, err := download(b.URL)
+		switch {
+		case err == nil:
+			data, cached = downloaded, true
+			if err := writeAtomic(cache, data); err != nil {
+				log.Printf("Failed to cache %s: %v", b.URL, err)
+			}
+		case cached:
+			log.Printf("Failed to download %s, using cached copy: %v", b.URL, err)
+		default:
+			return nil, err
+		}
+	}
+	if !cached {
+		return nil, fmt.Errorf("battery %s is not cached and nibs is offline", b.Name)
+	}
+
+	sum := checksum(data)
+	for _, expected := range []string{pin.SHA256, b.SHA256} {
+		if expected != "" && !strings.EqualFold(expected, sum) {
+			return nil, fmt.Errorf("battery %s: checksum mismatch, expected %s got %s", b.Name, expected, sum)
+		}
+	}
+	return data, nil
+}
+
+func download(url string) ([]byte, error) {
+	resp, err := http.Get(url)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("failed to download %s: bad status %s", url, resp.Status)
+	}
+
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to download %s: %w", url, err)
+	}
+	return data, nil
+}
+
+func checksum(data []byte) string {
+	sum := sha256.Sum256(data)
+	return hex.EncodeToString(sum[:])
+}
+
+// writeAtomic writes data to a temporary file first and renames it into place
+func writeAtomic(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".download-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
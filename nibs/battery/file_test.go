@@ -0,0 +1,88 @@
+package battery
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+)
+
+func TestInstallFileCacheAndForce(t *testing.T) {
+	content := "return 'pico'"
+	var fail atomic.Bool
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if fail.Load() {
+			http.Error(w, "gone", http.StatusInternalServerError)
+			return
+		}
+		w.Write([]byte(content))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	opts := Options{CacheDir: t.TempDir()}
+	b := Battery{Name: "pico", Source: SourceFile, URL: srv.URL + "/pico.lua", Target: "pico.lua"}
+
+	pin, err := b.Install(dir, Pin{}, opts)
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if pin.SHA256 != checksum([]byte(content)) {
+		t.Errorf("unexpected pin %+v", pin)
+	}
+
+	// installing the same version again is a no-op
+	if _, err := b.Install(dir, Pin{}, opts); err != nil {
+		t.Fatalf("second Install: %v", err)
+	}
+
+	// a failed download must not destroy the existing file
+	fail.Store(true)
+	if err := os.WriteFile(filepath.Join(dir, "pico.lua"), []byte("local changes"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Install(dir, Pin{}, opts); !errors.Is(err, ErrExists) {
+		t.Fatalf("expected ErrExists, got %v", err)
+	}
+	data, _ := os.ReadFile(filepath.Join(dir, "pico.lua"))
+	if string(data) != "local changes" {
+		t.Errorf("existing file was modified: %q", data)
+	}
+
+	// the cached copy is used when offline
+	opts.Offline = true
+	opts.Force = true
+	if _, err := b.Install(dir, pin, opts); err != nil {
+		t.Fatalf("offline Install: %v", err)
+	}
+	data, _ = os.ReadFile(filepath.Join(dir, "pico.lua"))
+	if string(data) != content {
+		t.Errorf("got %q, want %q", data, content)
+	}
+
+	// no leftovers from staging
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("expected only pico.lua in project, got %v", entries)
+	}
+}
+
+func TestInstallFileChecksumMismatch(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("tampered"))
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	b := Battery{Name: "pico", Source: SourceFile, URL: srv.URL + "/pico.lua", Target: "pico.lua"}
+	_, err := b.Install(dir, Pin{SHA256: checksum([]byte("original"))}, Options{CacheDir: t.TempDir()})
+	if err == nil {
+		t.Fatal("expected checksum mismatch")
+	}
+	if _, err := os.Stat(filepath.Join(dir, "pico.lua")); !os.IsNotExist(err) {
+		t.Errorf("file should not have been written")
+	}
+}
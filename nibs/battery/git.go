@@ -0,0 +1,179 @@
+package battery
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// fetchGit writes the selected files of the pinned commit (or the resolved ref) to dst.
+// Repositories are mirrored into the cache so they can be installed again without network access.
+func (b Battery) fetchGit(dst string, pin Pin, opts Options) (Pin, error) {
+	repo, err := b.openGitCache(pin, opts)
+	if err != nil {
+		return Pin{}, err
+	}
+
+	hash, err := resolveCommit(repo, b.Ref, pin.Commit)
+	if err != nil {
+		return Pin{}, fmt.Errorf("battery %s: %w", b.Name, err)
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return Pin{}, fmt.Errorf("battery %s: %w", b.Name, err)
+	}
+	tree, err := commit.Tree()
+	if err != nil {
+		return Pin{}, err
+	}
+	if b.Subdir != "" {
+		tree, err = tree.Tree(path.Clean(b.Subdir))
+		if err != nil {
+			return Pin{}, fmt.Errorf("battery %s: subdir %s: %w", b.Name, b.Subdir, err)
+		}
+	}
+
+	if len(b.Files) == 0 {
+		err = tree.Files().ForEach(func(f *object.File) error {
+			return writeGitFile(f, filepath.Join(dst, filepath.FromSlash(f.Name)))
+		})
+	} else {
+		for _, name := range b.Files {
+			f, ferr := tree.File(path.Clean(name))
+			if ferr != nil {
+				return Pin{}, fmt.Errorf("battery %s: %s: %w", b.Name, name, ferr)
+			}
+			if err = writeGitFile(f, filepath.Join(dst, filepath.FromSlash(name))); err != nil {
+				break
+			}
+		}
+	}
+	if err != nil {
+		return Pin{}, fmt.Errorf("failed to vendor %s: %w", b.Name, err)
+	}
+	return Pin{Commit: hash.String()}, nil
+}
+
+// openGitCache opens the cached mirror of the repository, cloning or updating it unless we are offline.
+// Network errors are not fatal as long as the cache can still satisfy the request.
+func (b Battery) openGitCache(pin Pin, opts Options) (*git.Repository, error) {
+	cache, err := opts.cachePath("git", b.URL)
+	if err != nil {
+		return nil, err
+	}
+
+	repo, err := git.PlainOpen(cache)
+	if errors.Is(err, git.ErrRepositoryNotExists) {
+		if opts.Offline {
+			return nil, fmt.Errorf("battery %s is not cached and nibs is offline", b.Name)
+		}
+		return cloneGitCache(cache, b.URL)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	// a pinned commit that is already cached never changes, so we can skip the network
+	if opts.Offline || hasCommit(repo, pin.Commit) {
+		return repo, nil
+	}
+	err = repo.Fetch(&git.FetchOptions{Force: true, Tags: git.AllTags})
+	if err != nil && !errors.Is(err, git.NoErrAlreadyUpToDate) {
+		log.Printf("Failed to update %s, using cached copy: %v", b.URL, err)
+	}
+	return repo, nil
+}
+
+func cloneGitCache(cache, url string) (*git.Repository, error) {
+	if err := os.MkdirAll(filepath.Dir(cache), 0o755); err != nil {
+		return nil, err
+	}
+	// clone next to the final location so a failed clone never leaves a broken cache behind
+	tmp, err := os.MkdirTemp(filepath.Dir(cache), ".clone-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(tmp)
+
+	_, err = git.PlainClone(tmp, true, &git.CloneOptions{
+		URL:      url,
+		Mirror:   true,
+		Progress: os.Stdout,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to clone %s: %w", url, err)
+	}
+	if err := os.Rename(tmp, cache); err != nil {
+		return nil, err
+	}
+	return git.PlainOpen(cache)
+}
+
+func hasCommit(repo *git.Repository, commit string) bool {
+	if commit == "" {
+		return false
+	}
+	_, err := repo.CommitObject(plumbing.NewHash(commit))
+	return err == nil
+}
+
+// resolveCommit returns the commit to check out: the pinned commit if set, otherwise ref or HEAD
+func resolveCommit(repo *git.Repository, ref, commit string) (plumbing.Hash, error) {
+	if commit != "" {
+		if !hasCommit(repo, commit) {
+			return plumbing.ZeroHash, fmt.Errorf("pinned commit %s not found", commit)
+		}
+		return plumbing.NewHash(commit), nil
+	}
+	if ref == "" {
+		head, err := repo.Head()
+		if err != nil {
+			return plumbing.ZeroHash, err
+		}
+		return head.Hash(), nil
+	}
+	if hash, err := repo.ResolveRevision(plumbing.Revision(ref)); err == nil {
+		return *hash, nil
+	}
+	// annotated tags point to a tag object instead of a commit
+	if tagRef, err := repo.Tag(ref); err == nil {
+		if tag, err := repo.TagObject(tagRef.Hash()); err == nil {
+			if c, err := tag.Commit(); err == nil {
+				return c.Hash, nil
+			}
+		}
+	}
+	return plumbing.ZeroHash, fmt.Errorf("unknown ref %s", ref)
+}
+
+func writeGitFile(f *object.File, dst string) error {
+	if !filepath.IsLocal(filepath.FromSlash(f.Name)) || strings.Contains(f.Name, "\\") {
+		return fmt.Errorf("refusing to write %s outside of the battery", f.Name)
+	}
+	r, err := f.Reader()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		return err
+	}
+	out, err := os.Create(dst)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	_, err = io.Copy(out, r)
+	return err
+}
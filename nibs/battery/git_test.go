@@ -0,0 +1,149 @@
+package battery
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+// commitFiles writes the files into the work tree of repo and commits them
+func commitFiles(t *testing.T, repo *git.Repository, dir string, files map[string]string) plumbing.Hash {
+	t.Helper()
+	worktree, err := repo.Worktree()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := worktree.Add(name); err != nil {
+			t.Fatal(err)
+		}
+	}
+	signature := &object.Signature{Name: "nibs", Email: "nibs@example.com", When: time.Now()}
+	hash, err := worktree.Commit("update", &git.CommitOptions{Author: signature})
+	if err != nil {
+		t.Fatal(err)
+	}
+	return hash
+}
+
+// bareRepo creates a bare repository with two commits, v1.0 is a lightweight tag and v1.1 an annotated one
+func bareRepo(t *testing.T) (string, plumbing.Hash, plumbing.Hash) {
+	t.Helper()
+	src := t.TempDir()
+	repo, err := git.PlainInit(src, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	first := commitFiles(t, repo, src, map[string]string{
+		"README.md":     "hump",
+		"lib/class.lua": "return 'class v1'",
+		"lib/timer.lua": "return 'timer v1'",
+	})
+	if _, err := repo.CreateTag("v1.0", first, nil); err != nil {
+		t.Fatal(err)
+	}
+	second := commitFiles(t, repo, src, map[string]string{"lib/class.lua": "return 'class v2'"})
+	signature := &object.Signature{Name: "nibs", Email: "nibs@example.com", When: time.Now()}
+	if _, err := repo.CreateTag("v1.1", second, &git.CreateTagOptions{Tagger: signature, Message: "v1.1"}); err != nil {
+		t.Fatal(err)
+	}
+
+	bare := filepath.Join(t.TempDir(), "hump.git")
+	if _, err := git.PlainClone(bare, true, &git.CloneOptions{URL: src}); err != nil {
+		t.Fatal(err)
+	}
+	return bare, first, second
+}
+
+func readVendored(t *testing.T, dir, name string) string {
+	t.Helper()
+	data, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(name)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(data)
+}
+
+func TestInstallGit(t *testing.T) {
+	url, first, second := bareRepo(t)
+	opts := Options{CacheDir: t.TempDir()}
+
+	tests := []struct {
+		name    string
+		battery Battery
+		commit  plumbing.Hash
+		files   map[string]string
+	}{
+		{
+			name:    "tag with subdir",
+			battery: Battery{Ref: "v1.0", Subdir: "lib", Target: "hump"},
+			commit:  first,
+			files:   map[string]string{"hump/class.lua": "return 'class v1'", "hump/timer.lua": "return 'timer v1'"},
+		},
+		{
+			name:    "annotated tag",
+			battery: Battery{Ref: "v1.1", Subdir: "lib", Files: []string{"class.lua"}, Target: "lib/hump"},
+			commit:  second,
+			files:   map[string]string{"lib/hump/class.lua": "return 'class v2'"},
+		},
+		{
+			name:    "commit",
+			battery: Battery{Ref: first.String(), Files: []string{"lib/class.lua"}, Target: "hump"},
+			commit:  first,
+			files:   map[string]string{"hump/lib/class.lua": "return 'class v1'"},
+		},
+		{
+			name:    "default branch",
+			battery: Battery{Target: "hump"},
+			commit:  second,
+			files:   map[string]string{"hump/README.md": "hump", "hump/lib/class.lua": "return 'class v2'", "hump/lib/timer.lua": "return 'timer v1'"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			b := tt.battery
+			b.Name, b.Source, b.URL = "hump", SourceGit, url
+			pin, err := b.Install(dir, Pin{}, opts)
+			if err != nil {
+				t.Fatalf("Install: %v", err)
+			}
+			if pin.Commit != tt.commit.String() {
+				t.Errorf("pinned %s, want %s", pin.Commit, tt.commit)
+			}
+			if len(pin.Files) != len(tt.files) {
+				t.Errorf("vendored %v, want %v", pin.Files, tt.files)
+			}
+			for name, content := range tt.files {
+				if got := readVendored(t, dir, name); got != content {
+					t.Errorf("%s = %q, want %q", name, got, content)
+				}
+			}
+		})
+	}
+
+	// a pinned commit is installed from the cache once the repository is gone
+	dir := t.TempDir()
+	b := Battery{Name: "hump", Source: SourceGit, URL: url, Subdir: "lib", Target: "hump"}
+	if err := os.RemoveAll(url); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := b.Install(dir, Pin{Commit: first.String()}, opts); err != nil {
+		t.Fatalf("Install from cache: %v", err)
+	}
+	if got := readVendored(t, dir, "hump/class.lua"); got != "return 'class v1'" {
+		t.Errorf("class.lua = %q", got)
+	}
+}
@@ -0,0 +1,105 @@
+package battery
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+)
+
+// fetchLocal copies the selected files of a local folder, e.g. a library shared between projects.
+// A relative path is resolved against opts.BaseDir or the project. Local batteries have no version, the lock
+// file records the checksum of the source next to the checksums of the copied files.
+func (b Battery) fetchLocal(dst, projectDir string, opts Options) (Pin, error) {
+	files, isDir, err := b.readLocal(projectDir, opts)
+	if err != nil {
+		return Pin{}, err
+	}
+	pin := Pin{SHA256: sourceChecksum(files)}
+
+	// a single file is vendored as the target itself
+	if !isDir {
+		if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+			return Pin{}, err
+		}
+		return pin, os.WriteFile(dst, files[filepath.Base(b.Path)], 0o644)
+	}
+	return pin, b.vendor(dst, files)
+}
+
+// localChecksum returns the checksum of the current source of a local battery
+func (b Battery) localChecksum(projectDir string, opts Options) (string, error) {
+	files, _, err := b.readLocal(projectDir, opts)
+	if err != nil {
+		return "", err
+	}
+	return sourceChecksum(files), nil
+}
+
+// readLocal reads the files of a local folder keyed by their slash separated path, or a single file
+// keyed by its name. It reports whether the source is a folder.
+func (b Battery) readLocal(projectDir string, opts Options) (map[string][]byte, bool, error) {
+	base := opts.BaseDir
+	if base == "" {
+		base = projectDir
+	}
+	root := b.Path
+	if !filepath.IsAbs(root) {
+		root = filepath.Join(base, root)
+	}
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, false, fmt.Errorf("battery %s: %w", b.Name, err)
+	}
+	if !info.IsDir() {
+		data, err := os.ReadFile(root)
+		if err != nil {
+			return nil, false, err
+		}
+		return map[string][]byte{filepath.Base(root): data}, false, nil
+	}
+
+	files := map[string][]byte{}
+	err = filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if d.IsDir() && d.Name() == ".git" {
+			return filepath.SkipDir
+		}
+		if !d.Type().IsRegular() {
+			return nil
+		}
+		rel, err := filepath.Rel(root, p)
+		if err != nil {
+			return err
+		}
+		data, err := os.ReadFile(p)
+		if err != nil {
+			return err
+		}
+		files[filepath.ToSlash(rel)] = data
+		return nil
+	})
+	if err != nil {
+		return nil, false, fmt.Errorf("battery %s: %w", b.Name, err)
+	}
+	return files, true, nil
+}
+
+// sourceChecksum combines the names and contents of all files into a single sha256
+func sourceChecksum(files map[string][]byte) string {
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	h := sha256.New()
+	for _, name := range names {
+		fmt.Fprintf(h, "%s %s\n", checksum(files[name]), name)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
@@ -0,0 +1,149 @@
+package battery
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestInstallLocal(t *testing.T) {
+	root := t.TempDir()
+	shared := filepath.Join(root, "shared")
+	for name, content := range map[string]string{
+		"src/vec.lua":   "return 'vec'",
+		"src/util.lua":  "return 'util'",
+		"docs/index.md": "docs",
+		".git/HEAD":     "ref: refs/heads/main",
+	} {
+		p := filepath.Join(shared, filepath.FromSlash(name))
+		os.MkdirAll(filepath.Dir(p), 0o755)
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	dir := filepath.Join(root, "game")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	b := Battery{Name: "shared", Source: SourceLocal, Path: "../shared", Subdir: "src", Target: "lib/shared"}
+	pin, err := b.Install(dir, Pin{}, Options{})
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if len(pin.Files) != 2 || pin.Commit != "" || pin.SHA256 == "" {
+		t.Errorf("unexpected pin %+v", pin)
+	}
+	if got := readVendored(t, dir, "lib/shared/vec.lua"); got != "return 'vec'" {
+		t.Errorf("vec.lua = %q", got)
+	}
+
+	// a change in the shared folder is picked up by a forced reinstall
+	os.WriteFile(filepath.Join(shared, "src", "vec.lua"), []byte("return 'vec2'"), 0o644)
+	if _, err := b.Install(dir, Pin{}, Options{Force: true}); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if got := readVendored(t, dir, "lib/shared/vec.lua"); got != "return 'vec2'" {
+		t.Errorf("vec.lua = %q", got)
+	}
+
+	// a single file
+	single := Battery{Name: "util", Source: SourceLocal, Path: filepath.Join(shared, "src", "util.lua"), Target: "util.lua"}
+	if _, err := single.Install(dir, Pin{}, Options{}); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if got := readVendored(t, dir, "util.lua"); got != "return 'util'" {
+		t.Errorf("util.lua = %q", got)
+	}
+
+	if err := (Battery{Name: "x", Source: SourceLocal, Target: "x"}).Validate(); err == nil {
+		t.Errorf("a local battery needs a path")
+	}
+}
+
+func TestInstallLocalSourceChanged(t *testing.T) {
+	root := t.TempDir()
+	shared := filepath.Join(root, "shared.lua")
+	if err := os.WriteFile(shared, []byte("return 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "game")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	b := Battery{Name: "shared", Source: SourceLocal, Path: shared, Target: "lib/shared.lua"}
+	pin, err := b.Install(dir, Pin{}, Options{})
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+
+	// the pinned source is installed as it is
+	again, err := b.Install(dir, pin, Options{})
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if again.SHA256 != pin.SHA256 {
+		t.Errorf("pin changed without a change of the source: %+v -> %+v", pin, again)
+	}
+
+	// a changed source replaces the unchanged vendored file
+	os.WriteFile(shared, []byte("return 2"), 0o644)
+	updated, err := b.Install(dir, pin, Options{})
+	if err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+	if got := readVendored(t, dir, "lib/shared.lua"); got != "return 2" {
+		t.Errorf("shared.lua = %q, want the changed source", got)
+	}
+	if updated.SHA256 == pin.SHA256 {
+		t.Errorf("pin did not record the changed source")
+	}
+
+	// edits to the vendored file are kept unless forced
+	os.WriteFile(filepath.Join(dir, "lib", "shared.lua"), []byte("return 'edited'"), 0o644)
+	os.WriteFile(shared, []byte("return 3"), 0o644)
+	if _, err := b.Install(dir, updated, Options{}); !errors.Is(err, ErrExists) {
+		t.Fatalf("Install: expected ErrExists, got %v", err)
+	}
+	if got := readVendored(t, dir, "lib/shared.lua"); got != "return 'edited'" {
+		t.Errorf("shared.lua = %q, want the edited file", got)
+	}
+}
+
+func TestInstallRestoresOldVersion(t *testing.T) {
+	root := t.TempDir()
+	shared := filepath.Join(root, "shared.lua")
+	if err := os.WriteFile(shared, []byte("return 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	dir := filepath.Join(root, "game")
+	if err := os.Mkdir(dir, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	b := Battery{Name: "shared", Source: SourceLocal, Path: shared, Target: "lib/shared.lua"}
+	if _, err := b.Install(dir, Pin{}, Options{}); err != nil {
+		t.Fatalf("Install: %v", err)
+	}
+
+	// moving the new version into place fails, the old one has to stay in the project
+	defer func() { rename = os.Rename }()
+	rename = func(from, to string) error {
+		if strings.Contains(from, ".nibs-stage-") && filepath.Base(from) == "shared.lua" {
+			return os.ErrPermission
+		}
+		return os.Rename(from, to)
+	}
+	os.WriteFile(shared, []byte("return 2"), 0o644)
+	if _, err := b.Install(dir, Pin{}, Options{Force: true}); !errors.Is(err, os.ErrPermission) {
+		t.Fatalf("Install: expected the rename error, got %v", err)
+	}
+	if got := readVendored(t, dir, "lib/shared.lua"); got != "return 1" {
+		t.Errorf("shared.lua = %q, want the old version", got)
+	}
+	entries, _ := os.ReadDir(dir)
+	if len(entries) != 1 {
+		t.Errorf("stage was not cleaned up: %v", entries)
+	}
+}
@@ -0,0 +1,42 @@
+package battery
+
+import (
+	"fmt"
+	"sort"
+)
+
+// registry contains the batteries that nibs knows about out of the box
+var registry = map[string]Battery{
+	// We usually want all files from hump so we vendor the whole repository
+	"hump": {
+		Source: SourceGit,
+		URL:    "https://github.com/vrld/hump.git",
+		Target: "hump",
+	},
+	// We only want the pico.lua file from the pico repository, so we just download it
+	"pico": {
+		Source: SourceFile,
+		URL:    "https://codeberg.org/usysrc/labs/raw/branch/main/pico/pico.lua",
+		Target: "pico.lua",
+	},
+}
+
+// Lookup returns the built-in battery with the given name
+func Lookup(name string) (Battery, error) {
+	b, ok := registry[name]
+	if !ok {
+		return Battery{}, fmt.Errorf("unknown battery: %s (available: %v)", name, Names())
+	}
+	b.Name = name
+	return b, nil
+}
+
+// Names returns the sorted names of all built-in batteries
+func Names() []string {
+	names := make([]string, 0, len(registry))
+	for name := range registry {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
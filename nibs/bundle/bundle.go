@@ -0,0 +1,122 @@
+// Package bundle writes .love files that are byte for byte reproducible.
+// Entries are sorted, carry normalized mode bits and a fixed modification time, so bundling the
+// same files twice gives the same archive.
+package bundle
+
+import (
+	"archive/zip"
+	"compress/flate"
+	"fmt"
+	"io"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// ModTime is the modification time of every entry, the earliest time a zip file can store
+var ModTime = time.Date(1980, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// DefaultStore are the extensions of files that are already compressed, deflating them again only costs time
+var DefaultStore = []string{".png", ".jpg", ".jpeg", ".ogg", ".oga", ".ogv", ".mp3", ".zip"}
+
+// Options configure how the bundle is written
+type Options struct {
+	// Level is the deflate level from 1 (fastest) to 9 (smallest), flate.DefaultCompression
+	// or 0 to store every file without compression
+	Level int
+	// Store lists the extensions of files that are stored without compression
+	Store []string
+	// Extra files are added to the bundle, replacing project files with the same name
+	Extra map[string][]byte
+}
+
+// DefaultOptions uses the default compression and stores already compressed assets
+func DefaultOptions() Options {
+	return Options{Level: flate.DefaultCompression, Store: DefaultStore}
+}
+
+// Write bundles the slash separated files, relative to dir, and the extra files into a .love archive
+func Write(w io.Writer, dir string, files []string, opts Options) error {
+	if opts.Level < flate.DefaultCompression || opts.Level > flate.BestCompression {
+		return fmt.Errorf("invalid compression level %d", opts.Level)
+	}
+
+	names := make([]string, 0, len(files)+len(opts.Extra))
+	seen := map[string]bool{}
+	for _, name := range files {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	for name := range opts.Extra {
+		if !seen[name] {
+			seen[name] = true
+			names = append(names, name)
+		}
+	}
+	sort.Strings(names)
+
+	archive := zip.NewWriter(w)
+	archive.RegisterCompressor(zip.Deflate, func(out io.Writer) (io.WriteCloser, error) {
+		return flate.NewWriter(out, opts.Level)
+	})
+	for _, name := range names {
+		var err error
+		if data, ok := opts.Extra[name]; ok {
+			err = addData(archive, name, 0o644, data, opts)
+		} else {
+			err = addFile(archive, dir, name, opts)
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return archive.Close()
+}
+
+// addFile adds a project file, only the executable bit of its mode is kept
+func addFile(archive *zip.Writer, dir, name string, opts Options) error {
+	p := filepath.Join(dir, filepath.FromSlash(name))
+	info, err := os.Stat(p)
+	if err != nil {
+		return err
+	}
+	data, err := os.ReadFile(p)
+	if err != nil {
+		return err
+	}
+	mode := os.FileMode(0o644)
+	if info.Mode()&0o111 != 0 {
+		mode = 0o755
+	}
+	return addData(archive, name, mode, data, opts)
+}
+
+func addData(archive *zip.Writer, name string, mode os.FileMode, data []byte, opts Options) error {
+	hdr := &zip.FileHeader{Name: name, Method: method(name, opts), Modified: ModTime}
+	hdr.SetMode(mode)
+	writer, err := archive.CreateHeader(hdr)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(data)
+	return err
+}
+
+// method decides whether a file is deflated or stored
+func method(name string, opts Options) uint16 {
+	if opts.Level == flate.NoCompression {
+		return zip.Store
+	}
+	ext := path.Ext(name)
+	for _, e := range opts.Store {
+		if strings.EqualFold(ext, "."+strings.TrimPrefix(e, ".")) {
+			return zip.Store
+		}
+	}
+	return zip.Deflate
+}
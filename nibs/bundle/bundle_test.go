@@ -0,0 +1,174 @@
+package bundle
+
+import (
+	"archive/zip"
+	"bytes"
+	"compress/flate"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing/object"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) []string {
+	t.Helper()
+	var names []string
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+	return names
+}
+
+func TestWrite(t *testing.T) {
+	dir := t.TempDir()
+	files := writeFiles(t, dir, map[string]string{
+		"main.lua":     strings.Repeat("print('hello')\n", 100),
+		"conf.lua":     "function love.conf(t) end",
+		"gfx/hero.png": strings.Repeat("PNG", 100),
+	})
+	opts := DefaultOptions()
+	opts.Extra = map[string][]byte{"conf.lua": []byte("-- replaced"), "nibs_build.lua": []byte("return {}")}
+
+	var first bytes.Buffer
+	if err := Write(&first, dir, files, opts); err != nil {
+		t.Fatal(err)
+	}
+
+	// the same files with other timestamps and in another order give the same bundle
+	later := time.Now().Add(time.Hour)
+	for _, name := range files {
+		if err := os.Chtimes(filepath.Join(dir, name), later, later); err != nil {
+			t.Fatal(err)
+		}
+	}
+	reversed := []string{files[2], files[1], files[0]}
+	var second bytes.Buffer
+	if err := Write(&second, dir, reversed, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(first.Bytes(), second.Bytes()) {
+		t.Fatal("bundles of the same files differ")
+	}
+
+	r, err := zip.NewReader(bytes.NewReader(first.Bytes()), int64(first.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, f := range r.File {
+		names = append(names, f.Name)
+		if !f.Modified.Equal(ModTime) {
+			t.Errorf("%s modified at %v", f.Name, f.Modified)
+		}
+		if f.Mode() != 0o644 {
+			t.Errorf("%s has mode %v", f.Name, f.Mode())
+		}
+		wantMethod := zip.Deflate
+		if f.Name == "gfx/hero.png" {
+			wantMethod = zip.Store
+		}
+		if f.Method != wantMethod {
+			t.Errorf("%s compressed with method %d, want %d", f.Name, f.Method, wantMethod)
+		}
+		if f.Name == "conf.lua" {
+			rc, _ := f.Open()
+			var data bytes.Buffer
+			data.ReadFrom(rc)
+			rc.Close()
+			if data.String() != "-- replaced" {
+				t.Errorf("conf.lua = %q", data.String())
+			}
+		}
+	}
+	if got := strings.Join(names, " "); got != "conf.lua gfx/hero.png main.lua nibs_build.lua" {
+		t.Errorf("entries = %s", got)
+	}
+}
+
+func TestWriteLevel(t *testing.T) {
+	dir := t.TempDir()
+	files := writeFiles(t, dir, map[string]string{"main.lua": "print('hello')"})
+
+	var out bytes.Buffer
+	if err := Write(&out, dir, files, Options{Level: flate.NoCompression}); err != nil {
+		t.Fatal(err)
+	}
+	r, err := zip.NewReader(bytes.NewReader(out.Bytes()), int64(out.Len()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if r.File[0].Method != zip.Store {
+		t.Errorf("level 0 must store files")
+	}
+
+	if err := Write(&out, dir, files, Options{Level: 12}); err == nil {
+		t.Errorf("expected an error for level 12")
+	}
+}
+
+func TestInfoLua(t *testing.T) {
+	info := Info{Commit: "abc123", Dirty: true, Time: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC), Version: "v1.0.0"}
+	want := "-- generated by nibs\nreturn {\n\tcommit = \"abc123\",\n\tdirty = true,\n\ttime = \"2024-05-01T12:00:00Z\",\n\tnibs = \"v1.0.0\",\n}\n"
+	if got := string(info.Lua()); got != want {
+		t.Errorf("Lua() = %q, want %q", got, want)
+	}
+}
+
+func TestReadInfo(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{"main.lua": "print('hello')"})
+
+	t.Setenv("SOURCE_DATE_EPOCH", "1700000000")
+	info, err := ReadInfo(dir, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Commit != "" || !info.Time.Equal(time.Unix(1700000000, 0)) || info.Version != "v1.0.0" {
+		t.Errorf("info outside of git = %+v", info)
+	}
+
+	t.Setenv("SOURCE_DATE_EPOCH", "")
+	repo, err := git.PlainInit(dir, false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	worktree, _ := repo.Worktree()
+	if _, err := worktree.Add("main.lua"); err != nil {
+		t.Fatal(err)
+	}
+	when := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	hash, err := worktree.Commit("initial", &git.CommitOptions{Author: &object.Signature{Name: "nibs", Email: "nibs@example.com", When: when}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	// untracked files don't count as changes
+	writeFiles(t, dir, map[string]string{"game.love": "PK"})
+
+	info, err = ReadInfo(dir, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Commit != hash.String() || info.Dirty || !info.Time.Equal(when) {
+		t.Errorf("info of a clean checkout = %+v", info)
+	}
+
+	writeFiles(t, dir, map[string]string{"main.lua": "print('changed')"})
+	info, err = ReadInfo(dir, "v1.0.0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !info.Dirty {
+		t.Errorf("modified main.lua must make the build dirty")
+	}
+}
@@ -0,0 +1,104 @@
+package bundle
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+)
+
+// InfoModule is the module name the build info is bundled as, games read it with require("nibs_build")
+const InfoModule = "nibs_build"
+
+// Info describes how a bundle was built
+type Info struct {
+	// Commit is the git commit of the project, empty outside of a git repository
+	Commit string
+	// Dirty is set when tracked files had uncommitted changes
+	Dirty bool
+	// Time is the build time
+	Time time.Time
+	// Version is the version of nibs
+	Version string
+}
+
+// ReadInfo collects the build info of the project in dir.
+// The build time is taken from SOURCE_DATE_EPOCH or the time of the commit, so that building
+// the same commit twice gives the same bundle. Outside of git the current time is used.
+func ReadInfo(dir, version string) (Info, error) {
+	info := Info{Version: version}
+	repo, err := git.PlainOpenWithOptions(dir, &git.PlainOpenOptions{DetectDotGit: true})
+	if err == nil {
+		err = readGit(repo, &info)
+	} else if errors.Is(err, git.ErrRepositoryNotExists) {
+		info.Time = time.Now()
+		err = nil
+	}
+	if err != nil {
+		return Info{}, fmt.Errorf("failed to read build info: %w", err)
+	}
+
+	if epoch := os.Getenv("SOURCE_DATE_EPOCH"); epoch != "" {
+		seconds, err := strconv.ParseInt(epoch, 10, 64)
+		if err != nil {
+			return Info{}, fmt.Errorf("invalid SOURCE_DATE_EPOCH %q", epoch)
+		}
+		info.Time = time.Unix(seconds, 0)
+	}
+	info.Time = info.Time.UTC()
+	return info, nil
+}
+
+func readGit(repo *git.Repository, info *Info) error {
+	head, err := repo.Head()
+	if err != nil {
+		// a repository without commits
+		info.Time = time.Now()
+		info.Dirty = true
+		return nil
+	}
+	commit, err := repo.CommitObject(head.Hash())
+	if err != nil {
+		return err
+	}
+	info.Commit = head.Hash().String()
+	info.Time = commit.Committer.When
+
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return err
+	}
+	// untracked files, like the previous bundle, don't make the build dirty
+	for _, s := range status {
+		if s.Worktree != git.Untracked && (s.Worktree != git.Unmodified || s.Staging != git.Unmodified) {
+			info.Dirty = true
+		}
+	}
+	return nil
+}
+
+// Lua returns the build info as a Lua module
+func (i Info) Lua() []byte {
+	var b strings.Builder
+	b.WriteString("-- generated by nibs\n")
+	b.WriteString("return {\n")
+	fmt.Fprintf(&b, "\tcommit = %s,\n", luaString(i.Commit))
+	fmt.Fprintf(&b, "\tdirty = %t,\n", i.Dirty)
+	fmt.Fprintf(&b, "\ttime = %s,\n", luaString(i.Time.Format(time.RFC3339)))
+	fmt.Fprintf(&b, "\tnibs = %s,\n", luaString(i.Version))
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+// luaString quotes s as a Lua string literal
+func luaString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
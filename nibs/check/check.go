@@ -0,0 +1,388 @@
+// Package check finds mistakes in the Lua sources of a project before they are bundled.
+// It reports syntax errors, require calls that cannot be resolved inside of the project and
+// assignments to globals that were probably meant to be locals.
+package check
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/yuin/gopher-lua/ast"
+	"github.com/yuin/gopher-lua/parse"
+)
+
+// Severity tells whether a problem breaks the game or is only suspicious
+type Severity int
+
+const (
+	Warning Severity = iota
+	Error
+)
+
+func (s Severity) String() string {
+	if s == Error {
+		return "error"
+	}
+	return "warning"
+}
+
+// Problem is a single finding in a Lua file
+type Problem struct {
+	File     string
+	Line     int
+	Severity Severity
+	Message  string
+}
+
+// String formats the problem as "file:line: severity: message"
+func (p Problem) String() string {
+	return fmt.Sprintf("%s:%d: %s: %s", p.File, p.Line, p.Severity, p.Message)
+}
+
+// Options configure the checks
+type Options struct {
+	// Globals may be assigned without a warning, in addition to the ones of Lua and LÖVE
+	Globals []string
+	// Generated are slash separated files that are added to the bundle, they can be required but are not checked
+	Generated []string
+}
+
+// Globals are the names defined by LuaJIT and LÖVE, assigning them is never accidental
+var Globals = []string{
+	"_G", "_VERSION", "arg", "assert", "bit", "collectgarbage", "coroutine", "debug", "dofile",
+	"error", "gcinfo", "getfenv", "getmetatable", "io", "ipairs", "jit", "load", "loadfile",
+	"loadstring", "love", "math", "module", "newproxy", "next", "os", "package", "pairs", "pcall",
+	"print", "rawequal", "rawget", "rawset", "require", "select", "setfenv", "setmetatable",
+	"string", "table", "tonumber", "tostring", "type", "unpack", "xpcall",
+}
+
+// builtinModules can be required without a file in the project, modules below them as well
+var builtinModules = []string{
+	"bit", "enet", "ffi", "jit", "love", "ltn12", "mime", "socket", "string.buffer", "table.clear",
+	"table.new", "utf8", "https",
+	// the build info nibs adds to every bundle
+	"nibs_build",
+}
+
+// Files checks the slash separated files, relative to dir, that are .lua files.
+// Required modules are resolved against the given files, as those are the ones the game can load.
+// Problems are sorted by file and line.
+func Files(dir string, files []string, opts Options) ([]Problem, error) {
+	available := map[string]bool{}
+	for _, file := range files {
+		available[file] = true
+	}
+	for _, file := range opts.Generated {
+		available[file] = true
+	}
+
+	var problems []Problem
+	for _, file := range files {
+		if path.Ext(file) != ".lua" {
+			continue
+		}
+		src, err := os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+		if err != nil {
+			return nil, err
+		}
+		found, requires := source(file, src, opts)
+		problems = append(problems, found...)
+		problems = append(problems, unresolved(file, requires, available)...)
+	}
+	sort.SliceStable(problems, func(i, j int) bool {
+		if problems[i].File != problems[j].File {
+			return problems[i].File < problems[j].File
+		}
+		if problems[i].Line != problems[j].Line {
+			return problems[i].Line < problems[j].Line
+		}
+		return problems[i].Message < problems[j].Message
+	})
+	return problems, nil
+}
+
+// Source checks a single file for syntax errors and accidental globals
+func Source(file string, src []byte, opts Options) []Problem {
+	problems, _ := source(file, src, opts)
+	return problems
+}
+
+// source also returns the modules required with a string literal and the line of the first require
+func source(file string, src []byte, opts Options) ([]Problem, map[string]int) {
+	chunk, err := parse.Parse(bytes.NewReader(lua51(src)), file)
+	if err != nil {
+		return []Problem{syntaxError(file, src, err)}, nil
+	}
+	w := newWalker(file, opts)
+	w.block(chunk)
+	return w.problems, w.requires
+}
+
+// unresolved reports the required modules of a file that are neither built in nor part of the project
+func unresolved(file string, requires map[string]int, available map[string]bool) []Problem {
+	var problems []Problem
+	for module, line := range requires {
+		if isBuiltin(module) || resolves(module, available) {
+			continue
+		}
+		problems = append(problems, Problem{
+			File:     file,
+			Line:     line,
+			Severity: Error,
+			Message:  fmt.Sprintf("module '%s' not found, expected %s or %s", module, modulePath(module)+".lua", modulePath(module)+"/init.lua"),
+		})
+	}
+	return problems
+}
+
+// modulePath turns a module name into the slash separated path require searches for
+func modulePath(module string) string {
+	return strings.ReplaceAll(module, ".", "/")
+}
+
+// resolves reports whether require would find the module with the default require path of LÖVE
+func resolves(module string, available map[string]bool) bool {
+	p := modulePath(module)
+	return available[p+".lua"] || available[p+"/init.lua"]
+}
+
+func isBuiltin(module string) bool {
+	for _, b := range builtinModules {
+		if module == b || strings.HasPrefix(module, b+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func syntaxError(file string, src []byte, err error) Problem {
+	p := Problem{File: file, Severity: Error, Message: err.Error()}
+	var parseErr *parse.Error
+	if !errors.As(err, &parseErr) {
+		return p
+	}
+	p.Line = parseErr.Pos.Line
+	p.Message = parseErr.Message
+	if p.Line == parse.EOF {
+		// point at the last line, that is where the missing end or bracket belongs
+		p.Line = bytes.Count(bytes.TrimRight(src, "\n"), []byte("\n")) + 1
+		p.Message += " at end of file"
+	} else if parseErr.Token != "" {
+		p.Message += fmt.Sprintf(" near '%s'", parseErr.Token)
+	}
+	return p
+}
+
+// walker visits the syntax tree while keeping track of the local variables in scope
+type walker struct {
+	file     string
+	globals  map[string]bool
+	scopes   []map[string]bool
+	reported map[string]bool
+	problems []Problem
+	requires map[string]int
+}
+
+func newWalker(file string, opts Options) *walker {
+	w := &walker{
+		file:     file,
+		globals:  map[string]bool{},
+		reported: map[string]bool{},
+		requires: map[string]int{},
+	}
+	for _, g := range append(Globals, opts.Globals...) {
+		w.globals[g] = true
+	}
+	return w
+}
+
+func (w *walker) push(names ...string) {
+	scope := map[string]bool{}
+	for _, name := range names {
+		scope[name] = true
+	}
+	w.scopes = append(w.scopes, scope)
+}
+
+func (w *walker) pop() {
+	w.scopes = w.scopes[:len(w.scopes)-1]
+}
+
+func (w *walker) declare(names ...string) {
+	for _, name := range names {
+		w.scopes[len(w.scopes)-1][name] = true
+	}
+}
+
+func (w *walker) isLocal(name string) bool {
+	for i := len(w.scopes) - 1; i >= 0; i-- {
+		if w.scopes[i][name] {
+			return true
+		}
+	}
+	return false
+}
+
+// assign reports an assignment to a global, only the first one per name and file
+func (w *walker) assign(name string, line int) {
+	if w.isLocal(name) || w.globals[name] || w.reported[name] {
+		return
+	}
+	w.reported[name] = true
+	w.problems = append(w.problems, Problem{
+		File:     w.file,
+		Line:     line,
+		Severity: Warning,
+		Message:  fmt.Sprintf("assignment to global '%s', declare it local or add it to the check globals", name),
+	})
+}
+
+// block walks statements in a new scope
+func (w *walker) block(stmts []ast.Stmt, locals ...string) {
+	w.push(locals...)
+	for _, stmt := range stmts {
+		w.stmt(stmt)
+	}
+	w.pop()
+}
+
+func (w *walker) stmt(stmt ast.Stmt) {
+	switch s := stmt.(type) {
+	case *ast.AssignStmt:
+		w.exprs(s.Rhs)
+		for _, lhs := range s.Lhs {
+			if ident, ok := lhs.(*ast.IdentExpr); ok {
+				w.assign(ident.Value, s.Line())
+			} else {
+				w.expr(lhs)
+			}
+		}
+	case *ast.LocalAssignStmt:
+		// local function f() is parsed like local f = function() but f is visible inside of the function
+		if len(s.Names) == 1 && len(s.Exprs) == 1 {
+			if _, ok := s.Exprs[0].(*ast.FunctionExpr); ok {
+				w.declare(s.Names...)
+			}
+		}
+		w.exprs(s.Exprs)
+		w.declare(s.Names...)
+	case *ast.FuncCallStmt:
+		w.expr(s.Expr)
+	case *ast.DoBlockStmt:
+		w.block(s.Stmts)
+	case *ast.WhileStmt:
+		w.expr(s.Condition)
+		w.block(s.Stmts)
+	case *ast.RepeatStmt:
+		// the condition can see the locals of the body
+		w.push()
+		for _, stmt := range s.Stmts {
+			w.stmt(stmt)
+		}
+		w.expr(s.Condition)
+		w.pop()
+	case *ast.IfStmt:
+		w.expr(s.Condition)
+		w.block(s.Then)
+		w.block(s.Else)
+	case *ast.NumberForStmt:
+		w.expr(s.Init)
+		w.expr(s.Limit)
+		if s.Step != nil {
+			w.expr(s.Step)
+		}
+		w.block(s.Stmts, s.Name)
+	case *ast.GenericForStmt:
+		w.exprs(s.Exprs)
+		w.block(s.Stmts, s.Names...)
+	case *ast.FuncDefStmt:
+		if ident, ok := s.Name.Func.(*ast.IdentExpr); ok {
+			w.assign(ident.Value, s.Line())
+		} else {
+			w.expr(s.Name.Func)
+		}
+		if s.Name.Receiver != nil {
+			w.expr(s.Name.Receiver)
+		}
+		w.function(s.Func, s.Name.Method != "")
+	case *ast.ReturnStmt:
+		w.exprs(s.Exprs)
+	}
+}
+
+func (w *walker) function(f *ast.FunctionExpr, method bool) {
+	params := f.ParList.Names
+	if method {
+		params = append([]string{"self"}, params...)
+	}
+	w.block(f.Stmts, params...)
+}
+
+func (w *walker) exprs(exprs []ast.Expr) {
+	for _, e := range exprs {
+		w.expr(e)
+	}
+}
+
+func (w *walker) expr(expr ast.Expr) {
+	switch e := expr.(type) {
+	case *ast.AttrGetExpr:
+		w.expr(e.Object)
+		w.expr(e.Key)
+	case *ast.TableExpr:
+		for _, field := range e.Fields {
+			if field.Key != nil {
+				w.expr(field.Key)
+			}
+			w.expr(field.Value)
+		}
+	case *ast.FuncCallExpr:
+		w.call(e)
+		if e.Func != nil {
+			w.expr(e.Func)
+		}
+		if e.Receiver != nil {
+			w.expr(e.Receiver)
+		}
+		w.exprs(e.Args)
+	case *ast.LogicalOpExpr:
+		w.expr(e.Lhs)
+		w.expr(e.Rhs)
+	case *ast.RelationalOpExpr:
+		w.expr(e.Lhs)
+		w.expr(e.Rhs)
+	case *ast.StringConcatOpExpr:
+		w.expr(e.Lhs)
+		w.expr(e.Rhs)
+	case *ast.ArithmeticOpExpr:
+		w.expr(e.Lhs)
+		w.expr(e.Rhs)
+	case *ast.UnaryMinusOpExpr:
+		w.expr(e.Expr)
+	case *ast.UnaryNotOpExpr:
+		w.expr(e.Expr)
+	case *ast.UnaryLenOpExpr:
+		w.expr(e.Expr)
+	case *ast.FunctionExpr:
+		w.function(e, false)
+	}
+}
+
+// call remembers require calls with a string literal, unless require is shadowed by a local
+func (w *walker) call(e *ast.FuncCallExpr) {
+	ident, ok := e.Func.(*ast.IdentExpr)
+	if !ok || ident.Value != "require" || w.isLocal("require") || len(e.Args) != 1 {
+		return
+	}
+	if name, ok := e.Args[0].(*ast.StringExpr); ok {
+		if _, seen := w.requires[name.Value]; !seen {
+			w.requires[name.Value] = e.Line()
+		}
+	}
+}
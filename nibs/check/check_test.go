@@ -0,0 +1,138 @@
+package check
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestSource(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		opts     Options
+		problems []string
+	}{
+		{
+			name: "locals and fields",
+			src: `local player = {}
+function player.update(dt) player.x = dt end
+function player:draw() self.drawn = true end
+local function step(n) if n > 0 then return step(n - 1) end end
+for i = 1, 3 do local x = i end
+for k, v in pairs({}) do print(k, v) end
+repeat local done = true until done
+function love.load() end`,
+		},
+		{
+			name: "globals",
+			src: `speed = 10
+local function f()
+	speed = 20
+	count, total = 1, 2
+end
+function helper() end
+local x
+x = 1`,
+			problems: []string{
+				"main.lua:1: warning: assignment to global 'speed', declare it local or add it to the check globals",
+				"main.lua:4: warning: assignment to global 'count', declare it local or add it to the check globals",
+				"main.lua:4: warning: assignment to global 'total', declare it local or add it to the check globals",
+				"main.lua:6: warning: assignment to global 'helper', declare it local or add it to the check globals",
+			},
+		},
+		{
+			name: "allowed globals",
+			src:  "Game = {}\nfunction Game.start() end",
+			opts: Options{Globals: []string{"Game"}},
+		},
+		{
+			name:     "locals end with their block",
+			src:      "do local x = 1 end\nx = 2",
+			problems: []string{"main.lua:2: warning: assignment to global 'x', declare it local or add it to the check globals"},
+		},
+		{
+			name:     "syntax error",
+			src:      "local x = 1\nx = = 2",
+			problems: []string{"main.lua:2: error: syntax error near '='"},
+		},
+		{
+			name:     "missing end",
+			src:      "function love.draw()\n\tif x then\nend\n",
+			problems: []string{"main.lua:3: error: syntax error at end of file"},
+		},
+		{
+			name: "luajit syntax",
+			src: `local big = 1ULL + 2LL * 0x10ull
+local z = 3i
+local f = 0x1p4 + 0x.8 + 0X1.8P-3
+local b = 0b101
+local s = "a\z
+          b" .. 'c\z  d' .. "\"1LL\"" -- 0x1p4
+local x1LL, t = 1, {[1]=2}
+speed = 0x1p4`,
+			problems: []string{"main.lua:8: warning: assignment to global 'speed', declare it local or add it to the check globals"},
+		},
+		{
+			name:     "syntax error after luajit syntax",
+			src:      "local s = 'a\\z\n  b'\nlocal x = 1LL\nx = = 2",
+			problems: []string{"main.lua:4: error: syntax error near '='"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var got []string
+			for _, p := range Source("main.lua", []byte(tt.src), tt.opts) {
+				got = append(got, p.String())
+			}
+			if strings.Join(got, "\n") != strings.Join(tt.problems, "\n") {
+				t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.problems, "\n"))
+			}
+		})
+	}
+}
+
+func TestFiles(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"main.lua": `local player = require("player")
+local hump = require("lib.hump")
+local socket = require("socket.http")
+local missing = require("enemies.boss")
+local dynamic = require("levels." .. 1)
+local palette = require("gfx.palette")`,
+		"player.lua":        `local require = function() end require("nope") return {}`,
+		"lib/hump/init.lua": "return {}",
+		"broken.lua":        "if then",
+		"gfx/hero.png":      "PNG",
+	}
+	var names []string
+	for name, content := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		names = append(names, name)
+	}
+
+	problems, err := Files(dir, names, Options{Generated: []string{"gfx/palette.lua"}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		"broken.lua:1: error: syntax error near 'then'",
+		"main.lua:4: error: module 'enemies.boss' not found, expected enemies/boss.lua or enemies/boss/init.lua",
+	}
+	if strings.Join(got, "\n") != strings.Join(want, "\n") {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
@@ -0,0 +1,131 @@
+package check
+
+import (
+	"bytes"
+	"strings"
+
+	"codeberg.org/usysrc/belt/nibs/luasrc"
+)
+
+// LÖVE runs on LuaJIT, but the parser only knows Lua 5.1. lua51 rewrites the
+// LuaJIT extensions to the syntax into Lua 5.1 that the parser accepts:
+//
+//   - 64 bit and imaginary number suffixes (1LL, 1ULL, 2i)
+//   - hexadecimal floats (0x1p4, 0x.8) and binary numbers (0b101)
+//   - the \z escape that skips the following whitespace in strings
+//
+// The values of the rewritten literals are not kept, only their lines, so
+// problems are still reported at the right place.
+func lua51(src []byte) []byte {
+	var out bytes.Buffer
+	out.Grow(len(src))
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '-' && i+1 < len(src) && src[i+1] == '-':
+			end := i + 2
+			if level, ok := luasrc.LongBracket(src, end); ok {
+				end = luasrc.LongEnd(src, end, level)
+			} else {
+				for end < len(src) && src[end] != '\n' {
+					end++
+				}
+			}
+			out.Write(src[i:end])
+			i = end
+		case c == '[':
+			end := i + 1
+			if level, ok := luasrc.LongBracket(src, i); ok {
+				end = luasrc.LongEnd(src, i, level)
+			}
+			out.Write(src[i:end])
+			i = end
+		case c == '"' || c == '\'':
+			i = shortString(&out, src, i)
+		case isIdent(c) && !isDigit(c):
+			end := i
+			for end < len(src) && isIdent(src[end]) {
+				end++
+			}
+			out.Write(src[i:end])
+			i = end
+		case isDigit(c) || c == '.' && i+1 < len(src) && isDigit(src[i+1]):
+			end := i
+			for end < len(src) && (isIdent(src[end]) || src[end] == '.' ||
+				(src[end] == '+' || src[end] == '-') && strings.ContainsRune("eEpP", rune(src[end-1]))) {
+				end++
+			}
+			out.WriteString(number(string(src[i:end])))
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+	return out.Bytes()
+}
+
+// number rewrites a LuaJIT number literal into one of the same length that Lua 5.1 can parse
+func number(lit string) string {
+	n := strings.ToLower(lit)
+	for _, suffix := range []string{"ull", "ll", "i"} {
+		if strings.HasSuffix(n, suffix) && len(n) > len(suffix) {
+			n = n[:len(n)-len(suffix)]
+			break
+		}
+	}
+	hex := strings.HasPrefix(n, "0x")
+	if hex && strings.ContainsAny(n, ".p") || strings.HasPrefix(n, "0b") {
+		n = "0"
+	}
+	if len(n) == len(lit) {
+		return lit
+	}
+	return n + strings.Repeat(" ", len(lit)-len(n))
+}
+
+// shortString copies the quoted string starting at i to out, replacing \z escapes, and returns the index after it
+func shortString(out *bytes.Buffer, src []byte, i int) int {
+	quote := src[i]
+	out.WriteByte(quote)
+	for i++; i < len(src); i++ {
+		c := src[i]
+		switch {
+		case c == quote:
+			out.WriteByte(c)
+			return i + 1
+		case c == '\n':
+			// unterminated, leave the error to the parser
+			return i
+		case c == '\\' && i+1 < len(src) && src[i+1] == 'z':
+			// the skipped whitespace turns into spaces and line continuations
+			out.WriteString("  ")
+			for i += 2; i < len(src) && isSpace(src[i]); i++ {
+				switch src[i] {
+				case '\n':
+					out.WriteString("\\\n")
+				case '\r':
+				default:
+					out.WriteByte(' ')
+				}
+			}
+			i--
+		case c == '\\' && i+1 < len(src):
+			out.Write(src[i : i+2])
+			i++
+		default:
+			out.WriteByte(c)
+		}
+	}
+	return i
+}
+
+func isDigit(c byte) bool { return c >= '0' && c <= '9' }
+
+func isIdent(c byte) bool {
+	return c == '_' || isDigit(c) || c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= 0x80
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\v' || c == '\f'
+}
@@ -1,30 +1,109 @@
 package cmd
 
 import (
-	"log"
+	"fmt"
 
 	"codeberg.org/usysrc/belt/nibs/battery"
+	"codeberg.org/usysrc/belt/nibs/manifest"
 	"github.com/spf13/cobra"
 )
 
 var addCmd = &cobra.Command{
-	Use:   "add [battery]",
-	Long:  "Vendors a battery(library, file, folder) to your current project folder. Currently supports hump and pico.",
+	Use:   "add [battery]...",
+	Long:  "Vendors a battery(library, file, folder) to your current project folder and records it in nibs.toml and nibs.lock. Batteries are looked up in nibs.toml first and then in the built-in registry.",
 	Short: "add a battery to project",
 	Args:  cobra.MinimumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		whatToAdd := args[0]
-		switch whatToAdd {
-		case "hump":
-			battery.Hump()
-		case "pico":
-			battery.Pico()
-		default:
-			log.Fatalf("Unknown item to add: %s", whatToAdd)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, lock, err := loadManifest(project.Root)
+		if err != nil {
+			return err
 		}
+		opts := batteryOptions(cmd)
+
+		for _, name := range args {
+			b, ok := m.Batteries[name]
+			if !ok {
+				b, err = battery.Lookup(name)
+				if err != nil {
+					return err
+				}
+			}
+			pin, err := b.Install(project.Source, battery.Pin{}, opts)
+			if err != nil {
+				return err
+			}
+			// only the table of a new battery is written, nibs.toml is otherwise left untouched
+			if !ok {
+				if err := manifest.SetBattery(project.Root, name, b); err != nil {
+					return fmt.Errorf("failed to write %s: %w", manifest.FileName, err)
+				}
+			}
+			// the lock is saved after every battery, so it matches the vendored files if a later one fails
+			lock.Batteries[name] = pin
+			if err := saveLock(project.Root, lock); err != nil {
+				return err
+			}
+			fmt.Printf("Added %s %s\n", name, pinString(pin))
+		}
+		return nil
 	},
 }
 
 func init() {
+	addBatteryFlags(addCmd)
 	rootCmd.AddCommand(addCmd)
 }
+
+// addBatteryFlags adds the flags that control fetching and installing batteries
+func addBatteryFlags(cmd *cobra.Command) {
+	cmd.Flags().BoolP("force", "f", false, "overwrite existing files")
+	cmd.Flags().Bool("offline", false, "only install from the cache")
+}
+
+func batteryOptions(cmd *cobra.Command) battery.Options {
+	force, _ := cmd.Flags().GetBool("force")
+	offline, _ := cmd.Flags().GetBool("offline")
+	return battery.Options{Force: force, Offline: offline, BaseDir: project.Root}
+}
+
+// pinString returns a short human readable version of a pin.
+// Local batteries have no version, their pins only record the vendored files.
+func pinString(pin battery.Pin) string {
+	switch {
+	case pin.Commit != "":
+		return "@" + shorten(pin.Commit)
+	case pin.SHA256 != "":
+		return "sha256:" + shorten(pin.SHA256)
+	case len(pin.Files) > 0:
+		return "(local)"
+	}
+	return ""
+}
+
+func shorten(s string) string {
+	if len(s) > 12 {
+		return s[:12]
+	}
+	return s
+}
+
+// loadManifest reads the manifest and lock file of the project in dir
+func loadManifest(dir string) (*manifest.Manifest, *manifest.Lock, error) {
+	m, err := manifest.Load(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", manifest.FileName, err)
+	}
+	lock, err := manifest.LoadLock(dir)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to read %s: %w", manifest.LockFileName, err)
+	}
+	return m, lock, nil
+}
+
+// saveLock writes the lock file of the project in dir
+func saveLock(dir string, lock *manifest.Lock) error {
+	if err := lock.Save(dir); err != nil {
+		return fmt.Errorf("failed to write %s: %w", manifest.LockFileName, err)
+	}
+	return nil
+}
@@ -0,0 +1,99 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"codeberg.org/usysrc/belt/nibs/manifest"
+)
+
+// batteryProject creates a project with the local battery "good" and the battery "missing" whose path does not exist
+func batteryProject(t *testing.T) string {
+	t.Helper()
+	root := t.TempDir()
+	if err := os.WriteFile(filepath.Join(root, "good.lua"), []byte("return 1"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	config := `[batteries.good]
+source = "local"
+path = "good.lua"
+target = "lib/good.lua"
+
+[batteries.missing]
+source = "local"
+path = "missing"
+target = "lib/missing"
+`
+	if err := os.WriteFile(filepath.Join(root, manifest.FileName), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	saved := project
+	project = settings{Root: root, Source: root}
+	t.Cleanup(func() { project = saved })
+	return root
+}
+
+// inSync checks that the lock file matches the vendored files of the battery
+func inSync(t *testing.T, root, name string) {
+	t.Helper()
+	m, lock, err := loadManifest(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	pin, ok := lock.Batteries[name]
+	if !ok {
+		t.Fatalf("%s is not in %s", name, manifest.LockFileName)
+	}
+	changes, err := m.Batteries[name].LocalChanges(root, pin)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !changes.Empty() {
+		t.Errorf("%s does not match its vendored files: %+v", name, changes)
+	}
+}
+
+func TestLockSavedPerBattery(t *testing.T) {
+	root := batteryProject(t)
+
+	// good is installed before missing fails
+	if err := installCmd.RunE(installCmd, nil); err == nil {
+		t.Fatal("install: expected an error for the missing battery")
+	}
+	inSync(t, root, "good")
+	os.Remove(filepath.Join(root, manifest.LockFileName))
+	if err := addCmd.RunE(addCmd, []string{"good", "missing"}); err == nil {
+		t.Fatal("add: expected an error for the missing battery")
+	}
+	inSync(t, root, "good")
+
+	os.WriteFile(filepath.Join(root, "good.lua"), []byte("return 2"), 0o644)
+	if err := updateCmd.RunE(updateCmd, []string{"good", "missing"}); err == nil {
+		t.Fatal("update: expected an error for the missing battery")
+	}
+	inSync(t, root, "good")
+	if data, _ := os.ReadFile(filepath.Join(root, "lib", "good.lua")); string(data) != "return 2" {
+		t.Errorf("good.lua was not updated: %q", data)
+	}
+
+	if err := removeCmd.RunE(removeCmd, []string{"good", "unknown"}); err == nil {
+		t.Fatal("remove: expected an error for the unknown battery")
+	}
+	m, lock, err := loadManifest(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := m.Batteries["good"]; ok {
+		t.Errorf("good is still in %s", manifest.FileName)
+	}
+	if _, ok := lock.Batteries["good"]; ok {
+		t.Errorf("good is still in %s", manifest.LockFileName)
+	}
+	if _, ok := m.Batteries["missing"]; !ok {
+		t.Errorf("missing was removed from %s", manifest.FileName)
+	}
+	if _, err := os.Stat(filepath.Join(root, "lib", "good.lua")); !os.IsNotExist(err) {
+		t.Errorf("vendored files of good were not deleted: %v", err)
+	}
+}
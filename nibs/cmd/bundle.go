@@ -1,107 +1,210 @@
 package cmd
 
 import (
-	"archive/zip"
-	"io"
+	"fmt"
 	"log"
 	"os"
 	"path/filepath"
-	"strings"
 
+	"codeberg.org/usysrc/belt/nibs/bundle"
+	"codeberg.org/usysrc/belt/nibs/ignore"
+	"codeberg.org/usysrc/belt/nibs/pipeline"
 	"github.com/spf13/cobra"
 )
 
+// compressionLevel is set by the --level flag and overrides the level of the manifest
+var compressionLevel *int
+
+// sessionInfo is the build info of a watch session, it is read once when watch starts because
+// the git status of a large worktree is too slow to compute on every rebuild
+var sessionInfo *bundle.Info
+
 var bundleCmd = &cobra.Command{
 	Use:   "bundle",
 	Short: "bundle the project into a .love file",
 	Args:  cobra.MaximumNArgs(1),
-	Run: func(cmd *cobra.Command, args []string) {
-		dir := "./"
-		outputFile := getOutputFile(cmd)
-		bundleProject(dir, outputFile)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir, outputFile := project.Source, project.Output
+		setCompressionLevel(cmd)
+		if dryRun, _ := cmd.Flags().GetBool("dry-run"); dryRun {
+			files, err := collectFiles(dir, outputFile)
+			if err != nil {
+				return fmt.Errorf("failed to collect files: %w", err)
+			}
+			for _, file := range files {
+				fmt.Println(file)
+			}
+			return nil
+		}
+		if err := runHook(outputFile, preBundle); err != nil {
+			return err
+		}
+		if noCheck, _ := cmd.Flags().GetBool("no-check"); !noCheck && !checkPassed(dir, outputFile) {
+			return fmt.Errorf("the project has errors")
+		}
+		if err := bundleProject(dir, outputFile); err != nil {
+			return err
+		}
+		return runHook(outputFile, postBundle)
 	},
 }
 
 func init() {
 	// add -o flag to specify output file
-	bundleCmd.Flags().StringP("output", "o", "game.love", "output file")
+	bundleCmd.Flags().StringP("output", "o", "", "output file (default <project directory>.love)")
+	bundleCmd.Flags().BoolP("dry-run", "n", false, "only list the files that would be bundled")
+	bundleCmd.Flags().Int("level", -1, "deflate level from 1 to 9, 0 stores every file without compression")
+	bundleCmd.Flags().Bool("no-check", false, "bundle even if the Lua files have errors")
 	// add bundle command to root command
 	rootCmd.AddCommand(bundleCmd)
 }
 
-func getOutputFile(cmd *cobra.Command) string {
-	// default set name of output file to directory name
-	currentDirectory, err := os.Getwd()
-	if err != nil {
-		currentDirectory = "game"
+// setCompressionLevel applies the --level flag if it was given
+func setCompressionLevel(cmd *cobra.Command) {
+	if cmd.Flags().Changed("level") {
+		level, _ := cmd.Flags().GetInt("level")
+		compressionLevel = &level
 	}
-	outputFile := filepath.Base(currentDirectory) + ".love"
+}
 
-	// read the output flag if it was set
-	if cmd.Flags().Changed("output") {
-		outputFile, _ = cmd.Flags().GetString("output")
-	}
-	return outputFile
+// bundleProject bundles the project into outputFile, leaving out the files and directories in exclude
+func bundleProject(dir, outputFile string, exclude ...string) error {
+	return bundleProjectWith(dir, outputFile, nil, exclude...)
 }
 
-func bundleProject(dir, outputFile string) {
+// bundleProjectWith bundles the project and adds extra files, replacing project files with the same name
+func bundleProjectWith(dir, outputFile string, extra map[string][]byte, exclude ...string) error {
 	log.Println("Bundling project...")
+	files, err := collectFiles(dir, append([]string{outputFile}, exclude...)...)
+	if err != nil {
+		return fmt.Errorf("failed to bundle project: %w", err)
+	}
+	files, opts, err := prepareBundle(dir, files, extra)
+	if err != nil {
+		return fmt.Errorf("failed to bundle project: %w", err)
+	}
+
 	out, err := os.Create(outputFile)
 	if err != nil {
-		log.Fatalf("Failed to create .love file: %v", err)
+		return fmt.Errorf("failed to create .love file: %w", err)
 	}
 	defer out.Close()
 
-	archive := zip.NewWriter(out)
-	defer archive.Close()
+	if err := bundle.Write(out, dir, files, opts); err != nil {
+		return fmt.Errorf("failed to bundle project: %w", err)
+	}
+	if err := out.Close(); err != nil {
+		return fmt.Errorf("failed to write .love file: %w", err)
+	}
+	log.Printf("Project bundled as %s", outputFile)
+	return nil
+}
 
-	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+// prepareBundle runs the built-in pipeline steps on the files and reads the compression settings from the manifest.
+// It returns the files that are bundled as they are, all generated files including the build info are part of the options.
+func prepareBundle(dir string, files []string, extra map[string][]byte) ([]string, bundle.Options, error) {
+	m := project.Manifest
+	files, generated, err := pipeline.Run(dir, files, m.Pipeline)
+	if err != nil {
+		return nil, bundle.Options{}, err
+	}
+	opts := bundle.DefaultOptions()
+	if m.Bundle.Level != nil {
+		opts.Level = *m.Bundle.Level
+	}
+	if compressionLevel != nil {
+		opts.Level = *compressionLevel
+	}
+	if m.Bundle.Store != nil {
+		opts.Store = m.Bundle.Store
+	}
+
+	opts.Extra = generated
+	if !m.Bundle.NoBuildInfo {
+		info, err := readBuildInfo(dir)
 		if err != nil {
-			return err
+			return nil, bundle.Options{}, err
 		}
+		opts.Extra[bundle.InfoModule+".lua"] = info.Lua()
+	}
+	for name, data := range extra {
+		opts.Extra[name] = data
+	}
+	return files, opts, nil
+}
 
-		// Skip directories and unnecessary files
-		if info.IsDir() || shouldIgnore(path) {
-			return nil
+// readBuildInfo returns the build info of the watch session or reads it from the project in dir
+func readBuildInfo(dir string) (bundle.Info, error) {
+	if sessionInfo != nil {
+		return *sessionInfo, nil
+	}
+	return bundle.ReadInfo(dir, version())
+}
+
+// bundleRules returns the rules deciding which files of dir belong in the bundle.
+// The outputs of nibs, files or directories, are never part of it.
+func bundleRules(dir string, outputs ...string) (*ignore.Rules, error) {
+	m := project.Manifest
+	rules, err := ignore.Load(dir, m.Bundle.Include, m.Bundle.Exclude)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", ignore.FileName, err)
+	}
+	// never bundle the previous bundle or release
+	for _, output := range outputs {
+		if rel, ok := relativeTo(dir, output); ok && rel != "." {
+			rules.Exclude("/" + rel)
 		}
+	}
+	return rules, nil
+}
+
+// collectFiles returns the slash separated paths, relative to dir, of all files that belong in the bundle
+func collectFiles(dir string, outputs ...string) ([]string, error) {
+	rules, err := bundleRules(dir, outputs...)
+	if err != nil {
+		return nil, err
+	}
 
-		// Add file to the archive
+	var files []string
+	err = filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 		relPath, err := filepath.Rel(dir, path)
 		if err != nil {
 			return err
 		}
-		return addFileToZip(archive, path, relPath)
-	})
-
-	if err != nil {
-		log.Fatalf("Failed to bundle project: %v", err)
-	}
-	log.Printf("Project bundled as %s", outputFile)
-}
+		if relPath == "." {
+			return nil
+		}
 
-func shouldIgnore(path string) bool {
-	// Example: Ignore git and temporary files
-	ignorePatterns := []string{".git", ".DS_Store", "~", ".swp"}
-	for _, pattern := range ignorePatterns {
-		if strings.Contains(path, pattern) {
-			return true
+		if rules.Ignored(relPath, info.IsDir()) {
+			if info.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
 		}
-	}
-	return false
+		if !info.IsDir() {
+			files = append(files, filepath.ToSlash(relPath))
+		}
+		return nil
+	})
+	return files, err
 }
 
-func addFileToZip(archive *zip.Writer, path, relPath string) error {
-	file, err := os.Open(path)
+// relativeTo returns the slash separated path of file relative to dir if it is inside of dir
+func relativeTo(dir, file string) (string, bool) {
+	absDir, err := filepath.Abs(dir)
 	if err != nil {
-		return err
+		return "", false
 	}
-	defer file.Close()
-
-	writer, err := archive.Create(relPath)
+	absFile, err := filepath.Abs(file)
 	if err != nil {
-		return err
+		return "", false
 	}
-
-	_, err = io.Copy(writer, file)
-	return err
+	rel, err := filepath.Rel(absDir, absFile)
+	if err != nil || !filepath.IsLocal(rel) {
+		return "", false
+	}
+	return filepath.ToSlash(rel), true
 }
@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"codeberg.org/usysrc/belt/nibs/bundle"
+	"codeberg.org/usysrc/belt/nibs/manifest"
+)
+
+func TestCollectFilesExcludesOutputs(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"main.lua", "out/game.love", "out/game-web/game.lua", "lib/out/util.lua"} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	saved := project
+	project = settings{Root: dir, Source: dir, Manifest: &manifest.Manifest{}}
+	t.Cleanup(func() { project = saved })
+
+	outDir := filepath.Join(dir, "out")
+	files, err := collectFiles(dir, filepath.Join(outDir, "game.love"), outDir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"lib/out/util.lua", "main.lua"}
+	if !slices.Equal(files, want) {
+		t.Errorf("collectFiles() = %v, want %v", files, want)
+	}
+
+	// an output outside of the project changes nothing
+	files, err = collectFiles(dir, filepath.Join(t.TempDir(), "game.love"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = []string{"lib/out/util.lua", "main.lua", "out/game-web/game.lua"}
+	if !slices.Equal(files, want) {
+		t.Errorf("collectFiles() = %v, want %v", files, want)
+	}
+}
+
+func TestReadBuildInfoOfSession(t *testing.T) {
+	dir := t.TempDir()
+	info, err := readBuildInfo(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info.Commit != "" || info.Version != version() {
+		t.Errorf("unexpected build info %+v outside of git", info)
+	}
+
+	// a watch session reuses its build info instead of reading the project again
+	t.Cleanup(func() { sessionInfo = nil })
+	sessionInfo = &bundle.Info{Commit: "abc", Version: "session"}
+	info, err = readBuildInfo(filepath.Join(dir, "missing"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info != *sessionInfo {
+		t.Errorf("readBuildInfo() = %+v, want the session info %+v", info, *sessionInfo)
+	}
+}
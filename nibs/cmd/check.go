@@ -0,0 +1,72 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+
+	"codeberg.org/usysrc/belt/nibs/check"
+	"codeberg.org/usysrc/belt/nibs/pipeline"
+	"github.com/spf13/cobra"
+)
+
+var checkCmd = &cobra.Command{
+	Use:   "check",
+	Short: "check the Lua files of the project for errors",
+	Long:  "Parses every Lua file that would be bundled and reports syntax errors, require calls that cannot be resolved inside of the project and assignments to globals. Globals that are meant to be global can be listed in the [check] section of nibs.toml.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		errorCount, err := checkProject(project.Source, project.Output)
+		if err != nil {
+			return err
+		}
+		if errorCount > 0 {
+			return fmt.Errorf("%d error(s) found", errorCount)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(checkCmd)
+}
+
+// checkProject checks the Lua files that belong in the bundle, prints the problems and returns the number of errors
+func checkProject(dir string, outputs ...string) (int, error) {
+	m := project.Manifest
+	files, err := collectFiles(dir, outputs...)
+	if err != nil {
+		return 0, err
+	}
+	opts := check.Options{Globals: m.Check.Globals, Generated: pipeline.Generated(files, m.Pipeline)}
+	problems, err := check.Files(dir, files, opts)
+	if err != nil {
+		return 0, err
+	}
+
+	errorCount := 0
+	for _, p := range problems {
+		fmt.Fprintln(os.Stderr, p)
+		if p.Severity == check.Error {
+			errorCount++
+		}
+	}
+	if len(problems) > 0 {
+		fmt.Fprintf(os.Stderr, "%d error(s), %d warning(s)\n", errorCount, len(problems)-errorCount)
+	}
+	return errorCount, nil
+}
+
+// checkPassed runs the checks before bundling and reports whether the project can be bundled
+func checkPassed(dir string, outputs ...string) bool {
+	errorCount, err := checkProject(dir, outputs...)
+	if err != nil {
+		log.Printf("Failed to check project: %v", err)
+		return false
+	}
+	if errorCount > 0 {
+		log.Printf("Not bundling, fix the errors above or use --no-check")
+		return false
+	}
+	return true
+}
@@ -0,0 +1,131 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+
+	"codeberg.org/usysrc/belt/nibs/manifest"
+	"github.com/spf13/cobra"
+)
+
+// settings are the effective settings of the project, from nibs.toml and the flags of the command
+type settings struct {
+	// Root is the directory of nibs.toml, or the working directory without one
+	Root string
+	// Manifest is the content of nibs.toml
+	Manifest *manifest.Manifest
+	// Source is the folder with main.lua that is bundled
+	Source string
+	// Output is the .love file
+	Output string
+	// Love is the love binary and LoveArgs are passed to it after the game
+	Love     string
+	LoveArgs []string
+	// Extensions of the files that trigger a rebuild in watch
+	Extensions []string
+}
+
+// project is loaded by the root command before any other command runs
+var project settings
+
+// loadProject finds nibs.toml upwards from the working directory and applies the flags of cmd on top of it
+func loadProject(cmd *cobra.Command) error {
+	root, err := manifest.Find(".")
+	if err != nil {
+		return err
+	}
+	m, err := manifest.Load(root)
+	if err != nil {
+		return fmt.Errorf("failed to read %s: %w", filepath.Join(root, manifest.FileName), err)
+	}
+
+	s := settings{
+		Root:       root,
+		Manifest:   m,
+		Source:     root,
+		Output:     filepath.Join(root, filepath.Base(root)+".love"),
+		Love:       "love",
+		LoveArgs:   m.Love.Args,
+		Extensions: defaultExtensions,
+	}
+	if m.Project.Source != "" {
+		s.Source = filepath.Join(root, m.Project.Source)
+	}
+	if m.Project.Output != "" {
+		s.Output = filepath.Join(root, m.Project.Output)
+	}
+	if m.Love.Path != "" {
+		s.Love = m.Love.Path
+	}
+	if len(m.Watch.Extensions) > 0 {
+		s.Extensions = m.Watch.Extensions
+	}
+
+	// flags are relative to the working directory
+	flags := cmd.Flags()
+	if flags.Changed("source") {
+		source, _ := flags.GetString("source")
+		if s.Source, err = filepath.Abs(source); err != nil {
+			return err
+		}
+	}
+	if flags.Lookup("output") != nil && flags.Changed("output") {
+		output, _ := flags.GetString("output")
+		if s.Output, err = filepath.Abs(output); err != nil {
+			return err
+		}
+	}
+	if flags.Lookup("love") != nil && flags.Changed("love") {
+		s.Love, _ = flags.GetString("love")
+	}
+	if flags.Lookup("ext") != nil && flags.Changed("ext") {
+		s.Extensions, _ = flags.GetStringSlice("ext")
+	}
+
+	project = s
+	return nil
+}
+
+// rootDir returns a path given by a flag as is, its default is relative to the project root
+func rootDir(cmd *cobra.Command, flag string) string {
+	dir, _ := cmd.Flags().GetString(flag)
+	if cmd.Flags().Changed(flag) || filepath.IsAbs(dir) {
+		return dir
+	}
+	return filepath.Join(project.Root, dir)
+}
+
+var configCmd = &cobra.Command{
+	Use:   "config",
+	Short: "print the effective settings of the project",
+	Long:  "Prints the settings nibs uses for the project, from nibs.toml (searched upwards from the working directory), the defaults and the given flags.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		config := filepath.Join(project.Root, manifest.FileName)
+		if _, err := os.Stat(config); err != nil {
+			config = "none"
+		}
+		bundle := project.Manifest.Bundle
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintf(w, "config\t%s\n", config)
+		fmt.Fprintf(w, "root\t%s\n", project.Root)
+		fmt.Fprintf(w, "source\t%s\n", project.Source)
+		fmt.Fprintf(w, "output\t%s\n", project.Output)
+		fmt.Fprintf(w, "love\t%s\n", strings.Join(append([]string{project.Love}, project.LoveArgs...), " "))
+		fmt.Fprintf(w, "watch extensions\t%s\n", strings.Join(project.Extensions, ", "))
+		fmt.Fprintf(w, "bundle include\t%s\n", strings.Join(bundle.Include, ", "))
+		fmt.Fprintf(w, "bundle exclude\t%s\n", strings.Join(bundle.Exclude, ", "))
+		return w.Flush()
+	},
+}
+
+func init() {
+	configCmd.Flags().StringP("output", "o", "", "output file (default <project directory>.love)")
+	configCmd.Flags().String("love", "", "path to the love binary")
+	configCmd.Flags().StringSlice("ext", nil, "file extensions that trigger a rebuild")
+	rootCmd.AddCommand(configCmd)
+}
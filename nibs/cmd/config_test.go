@@ -0,0 +1,69 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/spf13/cobra"
+)
+
+func TestLoadProject(t *testing.T) {
+	// the working directory is reported without symlinks, e.g. /private/var on macOS
+	root, err := filepath.EvalSymlinks(t.TempDir())
+	if err != nil {
+		t.Fatal(err)
+	}
+	nested := filepath.Join(root, "src", "lib")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+	config := "[project]\nsource = \"src\"\noutput = \"build/game.love\"\n\n[love]\npath = \"love11\"\n\n[watch]\nextensions = [\".lua\"]\n"
+	if err := os.WriteFile(filepath.Join(root, "nibs.toml"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	wd, _ := os.Getwd()
+	if err := os.Chdir(nested); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	newCommand := func() *cobra.Command {
+		cmd := &cobra.Command{}
+		cmd.Flags().String("source", "", "")
+		cmd.Flags().StringP("output", "o", "", "")
+		cmd.Flags().String("love", "", "")
+		cmd.Flags().StringSlice("ext", defaultExtensions, "")
+		return cmd
+	}
+
+	if err := loadProject(newCommand()); err != nil {
+		t.Fatal(err)
+	}
+	want := settings{
+		Root:       root,
+		Source:     filepath.Join(root, "src"),
+		Output:     filepath.Join(root, "build", "game.love"),
+		Love:       "love11",
+		Extensions: []string{".lua"},
+	}
+	if project.Root != want.Root || project.Source != want.Source || project.Output != want.Output ||
+		project.Love != want.Love || strings.Join(project.Extensions, ",") != ".lua" {
+		t.Errorf("project = %+v, want %+v", project, want)
+	}
+
+	// flags win over nibs.toml, paths are relative to the working directory
+	cmd := newCommand()
+	cmd.Flags().Set("output", "out.love")
+	cmd.Flags().Set("love", "love12")
+	cmd.Flags().Set("ext", "lua,png")
+	cmd.Flags().Set("source", ".")
+	if err := loadProject(cmd); err != nil {
+		t.Fatal(err)
+	}
+	if project.Output != filepath.Join(nested, "out.love") || project.Source != nested || project.Love != "love12" ||
+		strings.Join(project.Extensions, ",") != "lua,png" {
+		t.Errorf("project with flags = %+v", project)
+	}
+}
@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+
+	"codeberg.org/usysrc/belt/nibs/dist"
+	"github.com/spf13/cobra"
+)
+
+var distCmd = &cobra.Command{
+	Use:   "dist",
+	Short: "package the project for distribution",
+	Long: `Bundles the project and fuses it with a LÖVE runtime into a self-contained distribution.
+The runtime is a LÖVE release for the target platform that you downloaded before (a directory, .zip or .tar.gz), nibs never downloads it for you.
+
+  windows: love-11.5-win64.zip -> dist/<name>-windows.zip with <name>.exe
+  macos:   love-11.5-macos.zip -> dist/<name>-macos.zip with <name>.app
+  linux:   an extracted AppImage or love build -> dist/<name>-linux.tar.gz with a launcher script
+  web:     a love.js release directory -> dist/<name>-web/ ready to host, try it with nibs serve-web`,
+	Args: cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dir := project.Source
+		target, _ := cmd.Flags().GetString("target")
+		runtime, _ := cmd.Flags().GetString("runtime")
+		outDir := rootDir(cmd, "dir")
+		identifier, _ := cmd.Flags().GetString("identifier")
+		memory, _ := cmd.Flags().GetInt("memory")
+		if !slices.Contains(dist.Targets, target) {
+			return fmt.Errorf("unknown target %q, expected one of %v", target, dist.Targets)
+		}
+
+		setCompressionLevel(cmd)
+		name := strings.TrimSuffix(filepath.Base(project.Output), ".love")
+		if err := os.MkdirAll(outDir, 0o755); err != nil {
+			return err
+		}
+		loveFile := filepath.Join(outDir, name+".love")
+		if err := runHook(loveFile, preBundle); err != nil {
+			return err
+		}
+		if noCheck, _ := cmd.Flags().GetBool("no-check"); !noCheck && !checkPassed(dir, loveFile, outDir) {
+			return fmt.Errorf("the project has errors")
+		}
+		// the output directory holds the previous release, which must not end up in the new one
+		if err := bundleProject(dir, loveFile, outDir); err != nil {
+			return err
+		}
+		if err := runHook(loveFile, postBundle); err != nil {
+			return err
+		}
+
+		out, err := dist.Build(target, dist.Options{
+			Name:       name,
+			Love:       loveFile,
+			Runtime:    runtime,
+			OutDir:     outDir,
+			Identifier: identifier,
+			Memory:     memory * 1024 * 1024,
+		})
+		if err != nil {
+			return err
+		}
+		fmt.Printf("Created %s\n", out)
+		return nil
+	},
+}
+
+func init() {
+	distCmd.Flags().StringP("output", "o", "", "name of the game (default the name of the .love file)")
+	distCmd.Flags().StringP("target", "t", "", fmt.Sprintf("target platform (%s)", strings.Join(dist.Targets, "|")))
+	distCmd.Flags().StringP("runtime", "r", "", "path to a LÖVE release for the target platform")
+	distCmd.Flags().StringP("dir", "d", "dist", "output directory")
+	distCmd.Flags().String("identifier", "", "macOS bundle identifier (default org.love2d.<name>)")
+	distCmd.Flags().Int("level", -1, "deflate level of the .love file from 1 to 9, 0 stores every file without compression")
+	distCmd.Flags().Bool("no-check", false, "build even if the Lua files have errors")
+	distCmd.Flags().Int("memory", 0, "initial memory of the web build in MiB (default derived from the game size)")
+	distCmd.MarkFlagRequired("target")
+	rootCmd.AddCommand(distCmd)
+}
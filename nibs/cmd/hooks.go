@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// names of the hooks in the [hooks] section of nibs.toml
+const (
+	preBundle  = "pre_bundle"
+	postBundle = "post_bundle"
+)
+
+// runHook runs the shell commands of the hook in the project root, stopping at the first one that fails
+func runHook(outputFile, hook string) error {
+	commands := project.Manifest.Hooks.PreBundle
+	if hook == postBundle {
+		commands = project.Manifest.Hooks.PostBundle
+	}
+	if len(commands) == 0 {
+		return nil
+	}
+
+	absOutput, err := filepath.Abs(outputFile)
+	if err != nil {
+		return err
+	}
+	for _, command := range commands {
+		log.Printf("Running %s hook: %s", hook, command)
+		c := shellCommand(command)
+		c.Dir = project.Root
+		c.Stdout = os.Stdout
+		c.Stderr = os.Stderr
+		c.Env = append(os.Environ(), "NIBS_PROJECT_DIR="+project.Root, "NIBS_SOURCE_DIR="+project.Source, "NIBS_OUTPUT="+absOutput)
+		if err := c.Run(); err != nil {
+			return fmt.Errorf("%s hook %q failed: %w", hook, command, err)
+		}
+	}
+	return nil
+}
+
+func shellCommand(command string) *exec.Cmd {
+	if runtime.GOOS == "windows" {
+		return exec.Command("cmd", "/C", command)
+	}
+	return exec.Command("sh", "-c", command)
+}
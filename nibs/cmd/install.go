@@ -0,0 +1,39 @@
+package cmd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+)
+
+var installCmd = &cobra.Command{
+	Use:   "install",
+	Short: "install all batteries from nibs.toml",
+	Long:  "Reproduces the vendored batteries listed in nibs.toml at the exact versions recorded in nibs.lock. Batteries without a lock entry are resolved and added to the lock file. Batteries that are already installed are skipped.",
+	Args:  cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, lock, err := loadManifest(project.Root)
+		if err != nil {
+			return err
+		}
+		opts := batteryOptions(cmd)
+
+		for _, name := range m.Names() {
+			pin, err := m.Batteries[name].Install(project.Source, lock.Batteries[name], opts)
+			if err != nil {
+				return err
+			}
+			lock.Batteries[name] = pin
+			if err := saveLock(project.Root, lock); err != nil {
+				return err
+			}
+			fmt.Printf("Installed %s %s\n", name, pinString(pin))
+		}
+		return nil
+	},
+}
+
+func init() {
+	addBatteryFlags(installCmd)
+	rootCmd.AddCommand(installCmd)
+}
@@ -0,0 +1,49 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+)
+
+var listCmd = &cobra.Command{
+	Use:     "list",
+	Aliases: []string{"ls"},
+	Short:   "list the batteries of the project",
+	Long:    "Lists the batteries from nibs.toml with their pinned version and whether the vendored files were modified since they were added.",
+	Args:    cobra.NoArgs,
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, lock, err := loadManifest(project.Root)
+		if err != nil {
+			return err
+		}
+
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "NAME\tVERSION\tTARGET\tSTATUS")
+		for _, name := range m.Names() {
+			b := m.Batteries[name]
+			pin, locked := lock.Batteries[name]
+
+			status := "ok"
+			if !locked {
+				status = "not installed"
+			} else {
+				changes, err := b.LocalChanges(project.Source, pin)
+				if err != nil {
+					return fmt.Errorf("failed to check %s: %w", name, err)
+				}
+				if !changes.Empty() {
+					status = fmt.Sprintf("modified (%d added, %d modified, %d removed)", len(changes.Added), len(changes.Modified), len(changes.Removed))
+				}
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", name, pinString(pin), b.Target, status)
+		}
+		return w.Flush()
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(listCmd)
+}
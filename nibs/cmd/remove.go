@@ -0,0 +1,48 @@
+package cmd
+
+import (
+	"fmt"
+
+	"codeberg.org/usysrc/belt/nibs/manifest"
+	"github.com/spf13/cobra"
+)
+
+var removeCmd = &cobra.Command{
+	Use:     "remove [battery]...",
+	Aliases: []string{"rm"},
+	Short:   "remove a battery from project",
+	Long:    "Deletes the vendored files of a battery and removes it from nibs.toml and nibs.lock.",
+	Args:    cobra.MinimumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, lock, err := loadManifest(project.Root)
+		if err != nil {
+			return err
+		}
+
+		for _, name := range args {
+			b, ok := m.Batteries[name]
+			if !ok {
+				return fmt.Errorf("battery %s is not part of this project", name)
+			}
+			// nibs.toml and nibs.lock are updated together before the files are deleted,
+			// so they stay in step if a later battery fails
+			if err := manifest.RemoveBattery(project.Root, name); err != nil {
+				return fmt.Errorf("failed to write %s: %w", manifest.FileName, err)
+			}
+			delete(m.Batteries, name)
+			delete(lock.Batteries, name)
+			if err := saveLock(project.Root, lock); err != nil {
+				return err
+			}
+			if err := b.Remove(project.Source); err != nil {
+				return fmt.Errorf("failed to remove %s: %w", name, err)
+			}
+			fmt.Printf("Removed %s\n", name)
+		}
+		return nil
+	},
+}
+
+func init() {
+	rootCmd.AddCommand(removeCmd)
+}
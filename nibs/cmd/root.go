@@ -2,6 +2,7 @@ package cmd
 
 import (
 	"os"
+	"runtime/debug"
 
 	"github.com/spf13/cobra"
 )
@@ -11,11 +12,31 @@ var rootCmd = &cobra.Command{
 	Use:   "nibs",
 	Short: "nibs is a cli to manage LÖVE projects",
 	Long:  `nibs is a cli to manage LÖVE projects. It can add libraries to a LÖVE project, build it, run it, and package it for distribution.`,
+	// errors returned by commands are not usage errors, so don't print the usage for them
+	SilenceUsage: true,
+	// every command works on the project of the nearest nibs.toml
+	PersistentPreRunE: func(cmd *cobra.Command, args []string) error {
+		return loadProject(cmd)
+	},
 	// Uncomment the following line if your bare application
 	// has an action associated with it:
 	// Run: func(cmd *cobra.Command, args []string) { },
 }
 
+// Version of nibs, set at build time with -ldflags "-X codeberg.org/usysrc/belt/nibs/cmd.Version=v1.2.3"
+var Version = ""
+
+// version returns Version or the module version when nibs was installed with go install
+func version() string {
+	if Version != "" {
+		return Version
+	}
+	if info, ok := debug.ReadBuildInfo(); ok && info.Main.Version != "" && info.Main.Version != "(devel)" {
+		return info.Main.Version
+	}
+	return "dev"
+}
+
 // Execute adds all child commands to the root command and sets flags appropriately.
 // This is called by main.main(). It only needs to happen once to the rootCmd.
 func Execute() {
@@ -26,6 +47,9 @@ func Execute() {
 }
 
 func init() {
+	rootCmd.Version = version()
+	rootCmd.PersistentFlags().String("source", "", "folder with main.lua (default from nibs.toml or the project directory)")
+
 	// Here you will define your flags and configuration settings.
 	// Cobra supports persistent flags, which, if defined here,
 	// will be global for your application.
@@ -0,0 +1,65 @@
+package cmd
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"codeberg.org/usysrc/belt/nibs/diag"
+	"github.com/spf13/cobra"
+)
+
+var runCmd = &cobra.Command{
+	Use:   "run [-- game args...]",
+	Short: "run the project with LÖVE straight from the source folder",
+	Long:  "Runs love on the project folder without bundling it first. Lua errors are printed as compact file:line diagnostics and the exit code of love is passed on. Arguments after -- are passed to the game.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		loveArgs := append([]string{project.Source}, project.LoveArgs...)
+		loveArgs = append(loveArgs, args...)
+
+		code, err := runLove(project.Love, loveArgs)
+		if err != nil {
+			return fmt.Errorf("failed to run LÖVE: %w", err)
+		}
+		if code != 0 {
+			os.Exit(code)
+		}
+		return nil
+	},
+}
+
+func init() {
+	runCmd.Flags().String("love", "", "path to the love binary (default love from the PATH)")
+	rootCmd.AddCommand(runCmd)
+}
+
+// runLove runs love in the foreground, converting its errors to diagnostics, and returns its exit code
+func runLove(lovePath string, args []string) (int, error) {
+	love := exec.Command(lovePath, args...)
+	love.Stdin = os.Stdin
+	love.Stdout = os.Stdout
+	stderr, err := love.StderrPipe()
+	if err != nil {
+		return 0, err
+	}
+	if err := love.Start(); err != nil {
+		return 0, err
+	}
+
+	errorCount := 0
+	filterErr := diag.Filter(stderr, os.Stderr, func(diag.Diagnostic) { errorCount++ })
+	if errorCount > 0 {
+		fmt.Fprintf(os.Stderr, "%d error(s)\n", errorCount)
+	}
+
+	err = love.Wait()
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		return exitErr.ExitCode(), nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return 0, filterErr
+}
@@ -0,0 +1,56 @@
+package cmd
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"codeberg.org/usysrc/belt/nibs/dist"
+	"github.com/spf13/cobra"
+)
+
+var serveWebCmd = &cobra.Command{
+	Use:   "serve-web [dir]",
+	Short: "serve the web build of the project",
+	Long:  "Serves the folder created by nibs dist --target web (or dir) with the cross-origin isolation headers love.js needs for threads.",
+	Args:  cobra.MaximumNArgs(1),
+	RunE: func(cmd *cobra.Command, args []string) error {
+		port, _ := cmd.Flags().GetString("port")
+		outDir := rootDir(cmd, "dir")
+
+		webDir := ""
+		if len(args) > 0 {
+			webDir = args[0]
+		} else {
+			name := strings.TrimSuffix(filepath.Base(project.Output), ".love")
+			webDir = dist.WebDir(outDir, name)
+		}
+		if _, err := os.Stat(filepath.Join(webDir, "index.html")); err != nil {
+			return fmt.Errorf("no web build in %s, run nibs dist --target web first", webDir)
+		}
+		return serveWeb(webDir, port)
+	},
+}
+
+func init() {
+	serveWebCmd.Flags().StringP("port", "p", "8080", "port for the web server")
+	serveWebCmd.Flags().StringP("output", "o", "", "name of the game (default the name of the .love file)")
+	serveWebCmd.Flags().StringP("dir", "d", "dist", "output directory of nibs dist")
+	rootCmd.AddCommand(serveWebCmd)
+}
+
+// serveWeb serves dir like belt's serve tool, with COOP/COEP so SharedArrayBuffer is available
+func serveWeb(dir, port string) error {
+	fileServerHandler := http.FileServer(http.Dir(dir))
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Cross-Origin-Opener-Policy", "same-origin")
+		w.Header().Set("Cross-Origin-Embedder-Policy", "require-corp")
+		fileServerHandler.ServeHTTP(w, r)
+	})
+
+	log.Printf("Serving %s on http://localhost:%s\n", dir, port)
+	return http.ListenAndServe(":"+port, handler)
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"codeberg.org/usysrc/belt/nibs/spec"
+	"github.com/spf13/cobra"
+)
+
+var testCmd = &cobra.Command{
+	Use:   "test [specs...]",
+	Short: "run the Lua specs of the project",
+	Long:  "Runs the specs of the project headless, every *_spec.lua and test_*.lua in the source folder unless specs are given. The specs run in LÖVE without window, graphics and audio, or with a plain Lua binary given by --lua. Specs use describe, it, pending, before_each, after_each, assert_equal, assert_same and assert_error.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		files, err := specFiles(args)
+		if err != nil {
+			return err
+		}
+		if len(files) == 0 {
+			fmt.Println("No specs found")
+			return nil
+		}
+
+		luaPath, _ := cmd.Flags().GetString("lua")
+		results, err := spec.Run(spec.Options{
+			Dir:    project.Source,
+			Files:  files,
+			Love:   project.Love,
+			Lua:    luaPath,
+			Output: os.Stdout,
+		})
+		if err != nil {
+			return err
+		}
+
+		for _, r := range results {
+			if r.Status == spec.Fail {
+				fmt.Printf("FAIL %s: %s\n", r.File, r.Name)
+				fmt.Printf("    %s\n", r.Message)
+			}
+		}
+		counts := spec.Summary(results)
+		fmt.Printf("%d passed, %d failed, %d skipped\n", counts[spec.Pass], counts[spec.Fail], counts[spec.Skip])
+
+		if junit, _ := cmd.Flags().GetString("junit"); junit != "" {
+			if err := writeJUnit(junit, results); err != nil {
+				return err
+			}
+		}
+		if counts[spec.Fail] > 0 {
+			return fmt.Errorf("%d test(s) failed", counts[spec.Fail])
+		}
+		return nil
+	},
+}
+
+func init() {
+	testCmd.Flags().String("love", "", "path to the love binary (default love from the PATH)")
+	testCmd.Flags().String("lua", "", "run the specs with a plain lua or luajit binary instead of love")
+	testCmd.Flags().String("junit", "", "write the results as JUnit XML to this file")
+	rootCmd.AddCommand(testCmd)
+}
+
+// specFiles returns the given specs relative to the source folder, or all specs outside of the batteries
+func specFiles(args []string) ([]string, error) {
+	if len(args) == 0 {
+		var skip []string
+		for _, b := range project.Manifest.Batteries {
+			skip = append(skip, b.Target)
+		}
+		return spec.Discover(project.Source, skip)
+	}
+
+	files := make([]string, 0, len(args))
+	for _, arg := range args {
+		abs, err := filepath.Abs(arg)
+		if err != nil {
+			return nil, err
+		}
+		rel, err := filepath.Rel(project.Source, abs)
+		if err != nil || !filepath.IsLocal(rel) {
+			return nil, fmt.Errorf("%s is not inside of the source folder %s", arg, project.Source)
+		}
+		if _, err := os.Stat(abs); err != nil {
+			return nil, err
+		}
+		files = append(files, filepath.ToSlash(rel))
+	}
+	return files, nil
+}
+
+func writeJUnit(path string, results []spec.Result) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := spec.JUnit(f, results); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
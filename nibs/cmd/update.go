@@ -0,0 +1,97 @@
+package cmd
+
+import (
+	"fmt"
+
+	"codeberg.org/usysrc/belt/nibs/battery"
+	"github.com/spf13/cobra"
+)
+
+var updateCmd = &cobra.Command{
+	Use:   "update [battery]...",
+	Short: "update batteries to their newest version",
+	Long:  "Re-fetches the given batteries (or all batteries if none are given) at the newest commit of their ref and prints a summary of the changed files. Batteries whose vendored files were edited since they were installed are only updated with --force.",
+	RunE: func(cmd *cobra.Command, args []string) error {
+		m, lock, err := loadManifest(project.Root)
+		if err != nil {
+			return err
+		}
+		opts := batteryOptions(cmd)
+
+		names := args
+		if len(names) == 0 {
+			names = m.Names()
+		}
+		// check every battery before touching any files, so edits are never lost halfway through
+		replace := map[string]bool{}
+		local := map[string]battery.Changes{}
+		for _, name := range names {
+			b, ok := m.Batteries[name]
+			if !ok {
+				return fmt.Errorf("battery %s is not part of this project", name)
+			}
+			old, locked := lock.Batteries[name]
+			if !locked {
+				continue
+			}
+			changes, err := b.LocalChanges(project.Source, old)
+			if err != nil {
+				return fmt.Errorf("failed to check %s: %w", name, err)
+			}
+			if !changes.Empty() && !opts.Force {
+				return fmt.Errorf("battery %s has local changes (%d added, %d modified, %d removed), use --force to overwrite them", name, len(changes.Added), len(changes.Modified), len(changes.Removed))
+			}
+			// unchanged vendored files are replaced by the new version
+			replace[name] = true
+			local[name] = changes
+		}
+
+		for _, name := range names {
+			b := m.Batteries[name]
+			old := lock.Batteries[name]
+
+			opts := opts
+			opts.Force = opts.Force || replace[name]
+			pin, err := b.Install(project.Source, battery.Pin{}, opts)
+			if err != nil {
+				return err
+			}
+			lock.Batteries[name] = pin
+			if err := saveLock(project.Root, lock); err != nil {
+				return err
+			}
+
+			if c := local[name]; !c.Empty() {
+				fmt.Printf("Overwrote local changes to %s\n", name)
+				printChanges(c)
+			}
+			changes := battery.Diff(old.Files, pin.Files)
+			if changes.Empty() {
+				fmt.Printf("%s is up to date %s\n", name, pinString(pin))
+				continue
+			}
+			fmt.Printf("Updated %s %s -> %s\n", name, pinString(old), pinString(pin))
+			printChanges(changes)
+		}
+		return nil
+	},
+}
+
+func init() {
+	updateCmd.Flags().BoolP("force", "f", false, "overwrite local changes to the vendored files")
+	updateCmd.Flags().Bool("offline", false, "only update from the cache")
+	rootCmd.AddCommand(updateCmd)
+}
+
+func printChanges(c battery.Changes) {
+	for _, path := range c.Added {
+		fmt.Printf("  A %s\n", path)
+	}
+	for _, path := range c.Modified {
+		fmt.Printf("  M %s\n", path)
+	}
+	for _, path := range c.Removed {
+		fmt.Printf("  D %s\n", path)
+	}
+	fmt.Printf("  %d added, %d modified, %d removed\n", len(c.Added), len(c.Modified), len(c.Removed))
+}
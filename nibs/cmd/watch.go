@@ -1,6 +1,8 @@
 package cmd
 
 import (
+	"fmt"
+	"io/fs"
 	"log"
 	"os"
 	"os/exec"
@@ -9,105 +11,297 @@ import (
 	"sync"
 	"time"
 
+	"codeberg.org/usysrc/belt/nibs/bundle"
+	"codeberg.org/usysrc/belt/nibs/hot"
+	"codeberg.org/usysrc/belt/nibs/ignore"
 	"github.com/fsnotify/fsnotify"
 	"github.com/spf13/cobra"
 )
 
 var (
-	cmd        *exec.Cmd
-	done       = make(chan bool)
-	mu         sync.Mutex
-	running    = false
-	lastBundle time.Time
+	cmd     *exec.Cmd
+	done    = make(chan bool)
+	mu      sync.Mutex
+	running = false
+	// exited is closed once the current LÖVE process has exited
+	exited chan struct{}
+	// hotServer is set when watching with --hot
+	hotServer *hot.Server
+	// skipCheck is set when watching with --no-check
+	skipCheck bool
 )
 
+// debounceDuration is how long the project has to be quiet before it is rebuilt
 const debounceDuration = 500 * time.Millisecond
 
-func isRelevantChange(event fsnotify.Event) bool {
-	extensions := []string{".lua", ".png", ".jpg", ".ogg", ".wav", ".frag", ".vert"}
-	for _, ext := range extensions {
-		if strings.HasSuffix(event.Name, ext) {
+// agentTimeout is how long a game started with --hot has to connect its reload agent
+const agentTimeout = 5 * time.Second
+
+// defaultExtensions are the file types that trigger a rebuild
+var defaultExtensions = []string{".lua", ".png", ".jpg", ".ogg", ".wav", ".frag", ".vert"}
+
+// changeOps are the operations that change the contents of the project, chmod is not one of them
+const changeOps = fsnotify.Write | fsnotify.Create | fsnotify.Rename | fsnotify.Remove
+
+func isRelevantChange(event fsnotify.Event, extensions []string) bool {
+	if event.Op&changeOps == 0 {
+		return false
+	}
+	ext := filepath.Ext(event.Name)
+	for _, e := range extensions {
+		if strings.EqualFold(ext, "."+strings.TrimPrefix(e, ".")) {
 			return true
 		}
 	}
 	return false
 }
 
-func watchFiles(watcher *fsnotify.Watcher, dir, lovePath, outputFile string) {
+// debounce collects the events that are relevant and calls fire with all of them once no
+// further event arrived for delay. It returns when events is closed.
+func debounce(events <-chan fsnotify.Event, delay time.Duration, relevant func(fsnotify.Event) bool, fire func([]fsnotify.Event)) {
+	var pending []fsnotify.Event
+	var timer *time.Timer
+	var quiet <-chan time.Time
 	for {
 		select {
-		case event, ok := <-watcher.Events:
+		case event, ok := <-events:
 			if !ok {
+				if timer != nil {
+					timer.Stop()
+				}
 				return
 			}
-			if isRelevantChange(event) {
-				log.Printf("Change detected: %s %s", event.Name, event.Op)
-				// only bundle and start LÖVE if there hasn't been a change in the last 500ms
-				// and the change is a WRITE
-				if time.Since(lastBundle) > debounceDuration && event.Op&fsnotify.Write == fsnotify.Write {
-					lastBundle = time.Now()
-					bundleProject(dir, outputFile)
-					startLove(lovePath, outputFile)
-				}
+			if !relevant(event) {
+				continue
 			}
-		case err, ok := <-watcher.Errors:
-			if !ok {
-				return
+			pending = append(pending, event)
+			// restart the timer so we always build after the last change
+			if timer != nil {
+				timer.Stop()
 			}
+			timer = time.NewTimer(delay)
+			quiet = timer.C
+		case <-quiet:
+			quiet = nil
+			changes := pending
+			pending = nil
+			fire(changes)
+		}
+	}
+}
+
+// watchFiles rebuilds and restarts the game on changes to the files of dir that belong in the bundle
+func watchFiles(watcher *fsnotify.Watcher, rules *ignore.Rules, dir, lovePath, outputFile string, extensions []string) {
+	go func() {
+		for err := range watcher.Errors {
 			log.Printf("Watcher error: %v", err)
 		}
+	}()
+
+	relevant := func(event fsnotify.Event) bool {
+		rel, ok := relativeTo(dir, event.Name)
+		if !ok {
+			return false
+		}
+		if event.Op&fsnotify.Create != 0 {
+			// new directories have to be watched as well, including everything already inside of them
+			if info, err := os.Stat(event.Name); err == nil && info.IsDir() && !rules.Ignored(rel, true) {
+				if err := addSubdirectories(watcher, event.Name); err != nil {
+					log.Printf("Failed to watch %s: %v", event.Name, err)
+				}
+				// a directory moved or copied into the project brings its files along without events for them
+				if !containsRelevant(dir, event.Name, rules, extensions) {
+					return false
+				}
+				log.Printf("Change detected: %s %s", event.Name, event.Op)
+				return true
+			}
+		}
+		if rules.Ignored(rel, false) || !isRelevantChange(event, extensions) {
+			return false
+		}
+		log.Printf("Change detected: %s %s", event.Name, event.Op)
+		return true
+	}
+
+	debounce(watcher.Events, debounceDuration, relevant, func(changes []fsnotify.Event) {
+		// keep the running game until the errors are fixed
+		if !skipCheck && !checkPassed(dir, outputFile) {
+			return
+		}
+		if hotReloadAll(dir, changes) {
+			return
+		}
+		// a failed build keeps the running game as well
+		if err := bundleWatched(dir, outputFile); err != nil {
+			log.Print(err)
+			return
+		}
+		startLove(lovePath, outputFile)
+	})
+}
+
+// bundleWatched bundles the project and injects the reload agent in hot mode
+func bundleWatched(dir, outputFile string) error {
+	if hotServer == nil {
+		return bundleProject(dir, outputFile)
+	}
+	conf, err := os.ReadFile(filepath.Join(dir, "conf.lua"))
+	if err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("failed to read conf.lua: %w", err)
+	}
+	return bundleProjectWith(dir, outputFile, hot.Bundle(conf))
+}
+
+// hotReloadAll sends all changed files to the running game and reports whether that worked for every one of them.
+// Files that are gone after a remove or rename need a restart, editors that save via rename recreate them.
+func hotReloadAll(dir string, changes []fsnotify.Event) bool {
+	if hotServer == nil {
+		return false
+	}
+	reloaded := map[string]bool{}
+	for _, event := range changes {
+		if _, err := os.Stat(event.Name); err != nil {
+			return false
+		}
+		if reloaded[event.Name] {
+			continue
+		}
+		if !hotReload(dir, event.Name) {
+			return false
+		}
+		reloaded[event.Name] = true
+	}
+	return true
+}
+
+// hotReload sends the changed file to the running game and reports whether that worked
+func hotReload(dir, path string) bool {
+	rel, ok := relativeTo(dir, path)
+	if !ok || !hot.CanReload(rel) {
+		return false
+	}
+	data, err := os.ReadFile(path)
+	if err == nil {
+		err = hotServer.Reload(rel, data)
+	}
+	if err != nil {
+		log.Printf("Hot reload of %s failed, restarting LÖVE: %v", rel, err)
+		return false
 	}
+	log.Printf("Hot reloaded %s", rel)
+	return true
+}
+
+// containsRelevant reports whether the directory root holds a file that is not ignored and has one of the extensions
+func containsRelevant(dir, root string, rules *ignore.Rules, extensions []string) bool {
+	found := false
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, ok := relativeTo(dir, path)
+		if !ok {
+			return nil
+		}
+		if d.IsDir() {
+			if path != root && (strings.HasPrefix(d.Name(), ".") || rules.Ignored(rel, true)) {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if !rules.Ignored(rel, false) && isRelevantChange(fsnotify.Event{Name: path, Op: fsnotify.Create}, extensions) {
+			found = true
+			return filepath.SkipAll
+		}
+		return nil
+	})
+	return found
 }
 
 func addSubdirectories(watcher *fsnotify.Watcher, root string) error {
 	return filepath.Walk(root, func(path string, info os.FileInfo, err error) error {
-		// ignore directories that start with dot but not the root directory
-		if strings.HasPrefix(info.Name(), ".") && path != root {
-			return filepath.SkipDir
-		}
 		if err != nil {
 			return err
 		}
-		if info.IsDir() {
-			return watcher.Add(path)
+		if !info.IsDir() {
+			return nil
 		}
-		return nil
+		// ignore directories that start with dot but not the root directory
+		if strings.HasPrefix(info.Name(), ".") && path != root {
+			return filepath.SkipDir
+		}
+		return watcher.Add(path)
 	})
 }
 
 var watchCmd = &cobra.Command{
 	Use:   "watch",
 	Short: "Watch the project directory, bundle and run LÖVE when changes are detected",
-	Run: func(cmd *cobra.Command, args []string) {
-		dirToWatch := "./" // Change to your project directory
-		lovePath := "love"
-		outputFile := getOutputFile(cmd)
+	RunE: func(cmd *cobra.Command, args []string) error {
+		dirToWatch, lovePath, outputFile, extensions := project.Source, project.Love, project.Output, project.Extensions
+		rules, err := bundleRules(dirToWatch, outputFile)
+		if err != nil {
+			return err
+		}
 
-		// Bundle project and start LÖVE
-		bundleProject(dirToWatch, outputFile)
+		if hotMode, _ := cmd.Flags().GetBool("hot"); hotMode {
+			server, err := hot.Listen()
+			if err != nil {
+				return fmt.Errorf("failed to start hot reload server: %w", err)
+			}
+			defer server.Close()
+			hotServer = server
+		}
 
-		// Start LÖVE
-		startLove(lovePath, outputFile)
+		skipCheck, _ = cmd.Flags().GetBool("no-check")
+
+		if !project.Manifest.Bundle.NoBuildInfo {
+			info, err := bundle.ReadInfo(dirToWatch, version())
+			if err != nil {
+				return err
+			}
+			sessionInfo = &info
+		}
+
+		// files written by the hooks would trigger a rebuild, so they only run once
+		if err := runHook(outputFile, preBundle); err != nil {
+			return err
+		}
+
+		// Bundle project and start LÖVE, with errors it is started once they are fixed
+		if skipCheck || checkPassed(dirToWatch, outputFile) {
+			if err := bundleWatched(dirToWatch, outputFile); err != nil {
+				log.Print(err)
+			} else {
+				startLove(lovePath, outputFile)
+			}
+		}
 
 		// Initialize watcher
 		watcher, err := fsnotify.NewWatcher()
 		if err != nil {
-			log.Fatalf("Failed to create watcher: %v", err)
+			return fmt.Errorf("failed to create watcher: %w", err)
 		}
 		defer watcher.Close()
 
-		go watchFiles(watcher, dirToWatch, lovePath, outputFile)
-
 		if err := addSubdirectories(watcher, dirToWatch); err != nil {
-			log.Fatalf("Failed to add directories: %v", err)
+			return fmt.Errorf("failed to add directories: %w", err)
 		}
+		go watchFiles(watcher, rules, dirToWatch, lovePath, outputFile, extensions)
+
 		<-done
+		return nil
 	},
 }
 
 func init() {
 	// add -o flag to specify output file
-	watchCmd.Flags().StringP("output", "o", "game.love", "output file")
+	watchCmd.Flags().StringP("output", "o", "", "output file (default <project directory>.love)")
+	watchCmd.Flags().String("love", "", "path to the love binary (default love from the PATH)")
+	watchCmd.Flags().StringSlice("ext", defaultExtensions, "file extensions that trigger a rebuild")
+	watchCmd.Flags().Bool("hot", false, "reload changed Lua modules and assets without restarting LÖVE")
+	watchCmd.Flags().Bool("no-check", false, "bundle even if the Lua files have errors")
 	// add watch command
 	rootCmd.AddCommand(watchCmd)
 }
@@ -123,20 +317,22 @@ func startLove(lovePath, outputFile string) {
 		if err := cmd.Process.Kill(); err != nil {
 			log.Printf("Failed to stop LÖVE2D: %v", err)
 		} else {
-			cmd.Wait()
+			// wait until the process is killed
+			<-exited
 			log.Println("LÖVE2D stopped successfully")
 		}
 		running = false
 	}
-	// wait until the process is killed
-	for running {
-		time.Sleep(100 * time.Millisecond)
-	}
 
 	log.Println("Starting LÖVE2D with bundled project...")
 	cmd = exec.Command(lovePath, outputFile)
 	cmd.Stdout = os.Stdout
 	cmd.Stderr = os.Stderr
+	if hotServer != nil {
+		cmd.Env = append(os.Environ(), hotServer.Env())
+		// the agent of the old process is gone, the new one connects once the game runs
+		hotServer.Disconnect()
+	}
 
 	err := cmd.Start()
 	if err != nil {
@@ -147,13 +343,31 @@ func startLove(lovePath, outputFile string) {
 	running = true
 	log.Println("LÖVE2D started successfully")
 
+	// the goroutine only knows about its own process, cmd may be replaced by then
+	process, processExited := cmd, make(chan struct{})
+	exited = processExited
 	go func() {
-		if err := cmd.Wait(); err != nil && !strings.Contains(err.Error(), "signal: killed") {
+		if err := process.Wait(); err != nil && !strings.Contains(err.Error(), "signal: killed") {
 			log.Printf("LÖVE2D exited: %v", err)
 		}
+		close(processExited)
 		mu.Lock()
-		running = false
+		if cmd == process {
+			running = false
+		}
 		mu.Unlock()
 		log.Println("LÖVE2D stopped")
 	}()
+
+	if hotServer != nil {
+		go func() {
+			time.Sleep(agentTimeout)
+			mu.Lock()
+			current := cmd == process && running
+			mu.Unlock()
+			if current && !hotServer.Connected() {
+				log.Println("The hot reload agent did not connect, changes restart LÖVE instead")
+			}
+		}()
+	}
 }
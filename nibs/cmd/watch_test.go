@@ -0,0 +1,110 @@
+package cmd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"codeberg.org/usysrc/belt/nibs/ignore"
+	"github.com/fsnotify/fsnotify"
+)
+
+func TestIsRelevantChange(t *testing.T) {
+	extensions := []string{".lua", "png"}
+	tests := []struct {
+		event    fsnotify.Event
+		relevant bool
+	}{
+		{fsnotify.Event{Name: "main.lua", Op: fsnotify.Write}, true},
+		{fsnotify.Event{Name: "lib/player.lua", Op: fsnotify.Create}, true},
+		{fsnotify.Event{Name: "hero.PNG", Op: fsnotify.Rename}, true},
+		{fsnotify.Event{Name: "old.lua", Op: fsnotify.Remove}, true},
+		{fsnotify.Event{Name: "main.lua", Op: fsnotify.Chmod}, false},
+		{fsnotify.Event{Name: "main.lua.swp", Op: fsnotify.Write}, false},
+		{fsnotify.Event{Name: "jump.ogg", Op: fsnotify.Write}, false},
+	}
+	for _, tt := range tests {
+		if got := isRelevantChange(tt.event, extensions); got != tt.relevant {
+			t.Errorf("isRelevantChange(%v) = %v, want %v", tt.event, got, tt.relevant)
+		}
+	}
+}
+
+func TestDebounce(t *testing.T) {
+	events := make(chan fsnotify.Event)
+	fired := make(chan []fsnotify.Event, 10)
+	relevant := func(e fsnotify.Event) bool { return e.Name != "ignored" }
+	go func() {
+		debounce(events, 50*time.Millisecond, relevant, func(changes []fsnotify.Event) {
+			fired <- changes
+		})
+		close(fired)
+	}()
+
+	// a burst of changes results in a single build after the last one
+	for _, name := range []string{"a.lua", "ignored", "b.lua", "a.lua"} {
+		events <- fsnotify.Event{Name: name, Op: fsnotify.Write}
+		time.Sleep(20 * time.Millisecond)
+	}
+	select {
+	case changes := <-fired:
+		if len(changes) != 3 || changes[2].Name != "a.lua" {
+			t.Errorf("unexpected changes %v", changes)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("debounce never fired")
+	}
+
+	// changes after a build are not dropped
+	events <- fsnotify.Event{Name: "c.lua", Op: fsnotify.Create}
+	select {
+	case changes := <-fired:
+		if len(changes) != 1 || changes[0].Name != "c.lua" {
+			t.Errorf("unexpected changes %v", changes)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("debounce never fired for the second change")
+	}
+
+	close(events)
+	if _, ok := <-fired; ok {
+		t.Error("expected no more builds")
+	}
+}
+
+func TestContainsRelevant(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{
+		"levels/one/map.lua",
+		"docs/readme.md",
+		"docs/.cache/old.lua",
+		"build/game.lua",
+		"art/drafts/hero.psd",
+		"art/final/hero.PNG",
+	} {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	rules := ignore.New(nil, []string{"/build/"})
+	extensions := []string{".lua", ".png"}
+
+	tests := map[string]bool{
+		"levels":     true,
+		"levels/one": true,
+		"docs":       false,
+		"build":      false,
+		"art":        true,
+		"art/drafts": false,
+	}
+	for name, want := range tests {
+		if got := containsRelevant(dir, filepath.Join(dir, filepath.FromSlash(name)), rules, extensions); got != want {
+			t.Errorf("containsRelevant(%s) = %v, want %v", name, got, want)
+		}
+	}
+}
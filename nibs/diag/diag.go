@@ -0,0 +1,120 @@
+// Package diag turns the Lua errors LÖVE prints to stderr into compact file:line diagnostics.
+package diag
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// Frame is a single entry of a stack traceback
+type Frame struct {
+	File     string
+	Line     int
+	Function string
+}
+
+// Diagnostic is an error raised by the game
+type Diagnostic struct {
+	File    string
+	Line    int
+	Message string
+	Trace   []Frame
+}
+
+// String formats the diagnostic as "file:line: message" followed by the frames of the project
+func (d Diagnostic) String() string {
+	var b strings.Builder
+	if d.File != "" {
+		fmt.Fprintf(&b, "%s:%d: %s", d.File, d.Line, d.Message)
+	} else {
+		b.WriteString(d.Message)
+	}
+	for _, f := range d.Trace {
+		fmt.Fprintf(&b, "\n    %s:%d", f.File, f.Line)
+		if f.Function != "" {
+			fmt.Fprintf(&b, " in %s", f.Function)
+		}
+	}
+	return b.String()
+}
+
+var (
+	// "main.lua:3: attempt to call ..." with an optional "Syntax error: " in front
+	location = regexp.MustCompile(`^(?:Syntax error: )?([^\s:\[\]"]+\.lua):(\d+): (.*)$`)
+	// "\tmain.lua:3: in function 'load'"
+	frame = regexp.MustCompile(`^([^\s:\[\]"]+\.lua):(\d+): in (.*)$`)
+)
+
+// parseMessage splits "file:line: message" into a diagnostic
+func parseMessage(msg string) Diagnostic {
+	m := location.FindStringSubmatch(msg)
+	if m == nil {
+		return Diagnostic{Message: msg}
+	}
+	line, _ := strconv.Atoi(m[2])
+	return Diagnostic{File: m[1], Line: line, Message: m[3]}
+}
+
+// parseFrame returns the frame of a traceback line, frames inside of LÖVE itself or C functions are skipped
+func parseFrame(line string) (Frame, bool) {
+	m := frame.FindStringSubmatch(strings.TrimSpace(line))
+	if m == nil {
+		return Frame{}, false
+	}
+	n, _ := strconv.Atoi(m[2])
+	return Frame{File: m[1], Line: n, Function: m[3]}, true
+}
+
+// Filter copies r to w, replacing every error block of LÖVE with the compact form of its diagnostic.
+// Every diagnostic is also passed to found, which may be nil.
+func Filter(r io.Reader, w io.Writer, found func(Diagnostic)) error {
+	var current *Diagnostic
+	inTrace := false
+	flush := func() {
+		if current == nil {
+			return
+		}
+		fmt.Fprintln(w, current.String())
+		if found != nil {
+			found(*current)
+		}
+		current = nil
+		inTrace = false
+	}
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		switch {
+		case strings.HasPrefix(line, "Error: "):
+			flush()
+			d := parseMessage(strings.TrimPrefix(line, "Error: "))
+			current = &d
+		case current != nil && line == "stack traceback:":
+			inTrace = true
+		case current != nil && !inTrace && line == "":
+			// syntax errors end with an empty line before the traceback
+		case current != nil && inTrace && strings.HasPrefix(line, "\t"):
+			if f, ok := parseFrame(line); ok {
+				current.Trace = append(current.Trace, f)
+			}
+		case current != nil && strings.HasPrefix(line, "\t"):
+			// continuation of the message, e.g. when loading a module failed
+			if next := parseMessage(strings.TrimSpace(line)); current.File == "" && next.File != "" {
+				current.File, current.Line = next.File, next.Line
+				current.Message += " " + next.Message
+			} else {
+				current.Message += " " + strings.TrimSpace(line)
+			}
+		default:
+			flush()
+			fmt.Fprintln(w, line)
+		}
+	}
+	flush()
+	return scanner.Err()
+}
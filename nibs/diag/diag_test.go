@@ -0,0 +1,95 @@
+package diag
+
+import (
+	"strings"
+	"testing"
+)
+
+const runtimeError = `loading level 1
+Error: lib/player.lua:12: attempt to index local 'self' (a nil value)
+stack traceback:
+	[string "boot.lua"]:777: in function '__index'
+	lib/player.lua:12: in function 'update'
+	main.lua:8: in function 'update'
+	[string "boot.lua"]:612: in function <[string "boot.lua"]:594>
+	[C]: in function 'xpcall'
+bye
+`
+
+const syntaxError = `Error: Syntax error: main.lua:3: '=' expected near 'x'
+
+stack traceback:
+	[string "boot.lua"]:777: in function <[string "boot.lua"]:773>
+	[C]: in function 'require'
+`
+
+const moduleError = `Error: error loading module 'enemy' from file 'enemy.lua':
+	enemy.lua:4: unexpected symbol near ')'
+stack traceback:
+	[C]: in function 'require'
+	main.lua:1: in main chunk
+`
+
+func TestFilter(t *testing.T) {
+	tests := []struct {
+		name   string
+		input  string
+		output string
+		diags  []Diagnostic
+	}{
+		{
+			name:  "runtime error",
+			input: runtimeError,
+			output: "loading level 1\n" +
+				"lib/player.lua:12: attempt to index local 'self' (a nil value)\n" +
+				"    lib/player.lua:12 in function 'update'\n" +
+				"    main.lua:8 in function 'update'\n" +
+				"bye\n",
+			diags: []Diagnostic{{
+				File: "lib/player.lua", Line: 12, Message: "attempt to index local 'self' (a nil value)",
+				Trace: []Frame{{"lib/player.lua", 12, "function 'update'"}, {"main.lua", 8, "function 'update'"}},
+			}},
+		},
+		{
+			name:   "syntax error",
+			input:  syntaxError,
+			output: "main.lua:3: '=' expected near 'x'\n",
+			diags:  []Diagnostic{{File: "main.lua", Line: 3, Message: "'=' expected near 'x'"}},
+		},
+		{
+			name:   "module error",
+			input:  moduleError,
+			output: "enemy.lua:4: error loading module 'enemy' from file 'enemy.lua': unexpected symbol near ')'\n    main.lua:1 in main chunk\n",
+			diags: []Diagnostic{{
+				File: "enemy.lua", Line: 4, Message: "error loading module 'enemy' from file 'enemy.lua': unexpected symbol near ')'",
+				Trace: []Frame{{"main.lua", 1, "main chunk"}},
+			}},
+		},
+		{
+			name:   "no errors",
+			input:  "hello\nworld\n",
+			output: "hello\nworld\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var out strings.Builder
+			var diags []Diagnostic
+			if err := Filter(strings.NewReader(tt.input), &out, func(d Diagnostic) { diags = append(diags, d) }); err != nil {
+				t.Fatal(err)
+			}
+			if out.String() != tt.output {
+				t.Errorf("output:\n%q\nwant:\n%q", out.String(), tt.output)
+			}
+			if len(diags) != len(tt.diags) {
+				t.Fatalf("got %d diagnostics, want %d", len(diags), len(tt.diags))
+			}
+			for i := range diags {
+				if diags[i].String() != tt.diags[i].String() {
+					t.Errorf("diagnostic %d = %q, want %q", i, diags[i], tt.diags[i])
+				}
+			}
+		})
+	}
+}
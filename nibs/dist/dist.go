@@ -0,0 +1,192 @@
+package dist
+
+import (
+	"encoding/xml"
+	"fmt"
+	"os"
+	"regexp"
+	"strings"
+)
+
+// Targets lists the supported distribution targets
+var Targets = []string{"windows", "macos", "linux", "web"}
+
+// Options describe what to package and where to put it
+type Options struct {
+	// Name of the game, used for the executable and the archive
+	Name string
+	// Love is the path to the bundled .love file
+	Love string
+	// Runtime is the path to a LÖVE release (directory, .zip or .tar.gz) for the target platform
+	Runtime string
+	// OutDir is the directory the distribution is written to
+	OutDir string
+	// Identifier is the macOS bundle identifier, defaults to org.love2d.<name>
+	Identifier string
+	// Memory is the initial memory of the web build in bytes, derived from the game size if zero
+	Memory int
+}
+
+// Build creates the distribution for target and returns the path of the written archive
+func Build(target string, opts Options) (string, error) {
+	if opts.Runtime == "" {
+		return "", fmt.Errorf("target %s needs a LÖVE runtime (or love.js release for web), pass it with --runtime", target)
+	}
+	game, err := os.ReadFile(opts.Love)
+	if err != nil {
+		return "", err
+	}
+	runtime, err := readRuntime(opts.Runtime)
+	if err != nil {
+		return "", err
+	}
+
+	switch target {
+	case "windows":
+		return windows(opts, game, runtime)
+	case "macos":
+		return macos(opts, game, runtime)
+	case "linux":
+		return linux(opts, game, runtime)
+	case "web":
+		return web(opts, game, runtime)
+	}
+	return "", fmt.Errorf("unknown target %q, expected one of %v", target, Targets)
+}
+
+// windows fuses love.exe with the game into <name>.exe and ships it with the runtime dlls
+func windows(opts Options, game []byte, runtime []file) (string, error) {
+	var files []file
+	var exe *file
+	for i, f := range runtime {
+		switch strings.ToLower(f.name) {
+		case "love.exe":
+			exe = &runtime[i]
+		case "lovec.exe":
+			// the console version is only useful for debugging
+		default:
+			files = append(files, prefixed(opts.Name, f))
+		}
+	}
+	if exe == nil {
+		return "", fmt.Errorf("runtime %s does not contain love.exe", opts.Runtime)
+	}
+
+	fused := append(append([]byte{}, exe.data...), game...)
+	files = append(files, file{name: opts.Name + "/" + opts.Name + ".exe", mode: 0o755, data: fused})
+
+	out, err := outputPath(opts.OutDir, opts.Name+"-windows.zip")
+	if err != nil {
+		return "", err
+	}
+	return out, writeZip(out, files)
+}
+
+// macos renames love.app to <name>.app, adds the game to its resources and patches the Info.plist
+func macos(opts Options, game []byte, runtime []file) (string, error) {
+	app := ""
+	for _, f := range runtime {
+		if top, _, ok := strings.Cut(f.name, "/"); ok && strings.HasSuffix(top, ".app") {
+			app = top
+			break
+		}
+	}
+	if app == "" {
+		return "", fmt.Errorf("runtime %s does not contain an .app bundle", opts.Runtime)
+	}
+
+	identifier := opts.Identifier
+	if identifier == "" {
+		identifier = "org.love2d." + bundleSafe.ReplaceAllString(opts.Name, "-")
+	}
+
+	target := opts.Name + ".app"
+	var files []file
+	plist := false
+	for _, f := range runtime {
+		if !strings.HasPrefix(f.name, app+"/") {
+			continue
+		}
+		f.name = target + strings.TrimPrefix(f.name, app)
+		if f.name == target+"/Contents/Info.plist" {
+			f.data = patchInfoPlist(f.data, opts.Name, identifier)
+			plist = true
+		}
+		files = append(files, f)
+	}
+	if !plist {
+		return "", fmt.Errorf("runtime %s does not contain %s/Contents/Info.plist", opts.Runtime, app)
+	}
+	files = append(files, file{name: target + "/Contents/Resources/" + opts.Name + ".love", mode: 0o644, data: game})
+
+	out, err := outputPath(opts.OutDir, opts.Name+"-macos.zip")
+	if err != nil {
+		return "", err
+	}
+	return out, writeZip(out, files)
+}
+
+// linux ships the runtime next to the game with a launcher script in the style of an AppDir
+func linux(opts Options, game []byte, runtime []file) (string, error) {
+	var files []file
+	for _, f := range runtime {
+		files = append(files, prefixed(opts.Name, f))
+	}
+	files = append(files,
+		file{name: opts.Name + "/" + opts.Name + ".love", mode: 0o644, data: game},
+		file{name: opts.Name + "/" + opts.Name, mode: 0o755, data: []byte(launcher(opts.Name))},
+	)
+
+	out, err := outputPath(opts.OutDir, opts.Name+"-linux.tar.gz")
+	if err != nil {
+		return "", err
+	}
+	return out, writeTarGz(out, files)
+}
+
+func prefixed(dir string, f file) file {
+	f.name = dir + "/" + f.name
+	return f
+}
+
+var bundleSafe = regexp.MustCompile(`[^A-Za-z0-9.-]`)
+
+var (
+	plistIdentifier = regexp.MustCompile(`(<key>CFBundleIdentifier</key>\s*<string>)[^<]*(</string>)`)
+	plistName       = regexp.MustCompile(`(<key>CFBundleName</key>\s*<string>)[^<]*(</string>)`)
+	// love.app claims the .love file type, a fused game must not
+	plistExported = regexp.MustCompile(`(?s)\s*<key>UTExportedTypeDeclarations</key>\s*<array>.*?</array>`)
+)
+
+// patchInfoPlist sets the bundle name and identifier of love.app's Info.plist
+func patchInfoPlist(data []byte, name, identifier string) []byte {
+	s := string(data)
+	s = replaceValue(plistIdentifier, s, identifier)
+	s = replaceValue(plistName, s, name)
+	s = plistExported.ReplaceAllString(s, "")
+	return []byte(s)
+}
+
+// replaceValue replaces the value between the two groups of re with the escaped value
+func replaceValue(re *regexp.Regexp, s, value string) string {
+	var b strings.Builder
+	xml.EscapeText(&b, []byte(value))
+	escaped := strings.ReplaceAll(b.String(), "$", "$$")
+	return re.ReplaceAllString(s, "${1}"+escaped+"${2}")
+}
+
+// launcher returns a shell script that starts the game with the bundled runtime
+func launcher(name string) string {
+	return `#!/bin/sh
+# launcher generated by nibs
+HERE="$(dirname "$(readlink -f "$0")")"
+GAME="$HERE/` + name + `.love"
+for love in "$HERE/AppRun" "$HERE/bin/love" "$HERE/usr/bin/love" "$HERE/love"; do
+	if [ -x "$love" ]; then
+		exec "$love" "$GAME" "$@"
+	fi
+done
+echo "no LÖVE runtime found next to $0" >&2
+exit 1
+`
+}
@@ -0,0 +1,232 @@
+package dist
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"bytes"
+	"compress/gzip"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+const infoPlist = `<?xml version="1.0" encoding="UTF-8"?>
+<plist version="1.0">
+<dict>
+	<key>CFBundleIdentifier</key>
+	<string>org.love2d.love</string>
+	<key>CFBundleName</key>
+	<string>LÖVE</string>
+	<key>UTExportedTypeDeclarations</key>
+	<array>
+		<dict><key>UTTypeIdentifier</key><string>org.love2d.love-game</string></dict>
+	</array>
+</dict>
+</plist>
+`
+
+func writeTestZip(t *testing.T, path string, files []file) {
+	t.Helper()
+	if err := writeZip(path, files); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func readTestZip(t *testing.T, path string) map[string]*zip.File {
+	t.Helper()
+	r, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { r.Close() })
+	entries := map[string]*zip.File{}
+	for _, f := range r.File {
+		entries[f.Name] = f
+	}
+	return entries
+}
+
+func readAll(t *testing.T, f *zip.File) []byte {
+	t.Helper()
+	rc, err := f.Open()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer rc.Close()
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		t.Fatal(err)
+	}
+	return data
+}
+
+func setup(t *testing.T) Options {
+	t.Helper()
+	dir := t.TempDir()
+	love := filepath.Join(dir, "jam.love")
+	if err := os.WriteFile(love, []byte("GAME"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	return Options{Name: "jam", Love: love, OutDir: filepath.Join(dir, "dist")}
+}
+
+func TestWindows(t *testing.T) {
+	opts := setup(t)
+	opts.Runtime = filepath.Join(t.TempDir(), "love-11.5-win64.zip")
+	writeTestZip(t, opts.Runtime, []file{
+		{name: "love-11.5-win64/love.exe", mode: 0o755, data: []byte("EXE")},
+		{name: "love-11.5-win64/lovec.exe", mode: 0o755, data: []byte("EXEC")},
+		{name: "love-11.5-win64/SDL2.dll", mode: 0o644, data: []byte("DLL")},
+	})
+
+	out, err := Build("windows", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := readTestZip(t, out)
+	if got := string(readAll(t, entries["jam/jam.exe"])); got != "EXEGAME" {
+		t.Errorf("fused exe = %q", got)
+	}
+	if _, ok := entries["jam/SDL2.dll"]; !ok {
+		t.Errorf("missing dll, got %v", entries)
+	}
+	if _, ok := entries["jam/lovec.exe"]; ok {
+		t.Errorf("lovec.exe should not be shipped")
+	}
+}
+
+func TestMacOS(t *testing.T) {
+	opts := setup(t)
+	opts.Runtime = filepath.Join(t.TempDir(), "love-11.5-macos.zip")
+	writeTestZip(t, opts.Runtime, []file{
+		{name: "love.app/Contents/Info.plist", mode: 0o644, data: []byte(infoPlist)},
+		{name: "love.app/Contents/MacOS/love", mode: 0o755, data: []byte("BIN")},
+		{name: "love.app/Contents/Frameworks/Lua.framework/Lua", mode: fs.ModeSymlink | 0o777, data: []byte("Versions/Current/Lua")},
+	})
+
+	out, err := Build("macos", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	entries := readTestZip(t, out)
+	plist := string(readAll(t, entries["jam.app/Contents/Info.plist"]))
+	for _, want := range []string{"<string>org.love2d.jam</string>", "<string>jam</string>"} {
+		if !strings.Contains(plist, want) {
+			t.Errorf("Info.plist does not contain %s:\n%s", want, plist)
+		}
+	}
+	if strings.Contains(plist, "UTExportedTypeDeclarations") {
+		t.Errorf("Info.plist still exports the .love type:\n%s", plist)
+	}
+	if got := string(readAll(t, entries["jam.app/Contents/Resources/jam.love"])); got != "GAME" {
+		t.Errorf("game = %q", got)
+	}
+	if mode := entries["jam.app/Contents/MacOS/love"].Mode(); mode.Perm() != 0o755 {
+		t.Errorf("executable mode = %v", mode)
+	}
+	if mode := entries["jam.app/Contents/Frameworks/Lua.framework/Lua"].Mode(); mode&fs.ModeSymlink == 0 {
+		t.Errorf("symlink mode = %v", mode)
+	}
+}
+
+func TestLinux(t *testing.T) {
+	opts := setup(t)
+	opts.Runtime = t.TempDir()
+	if err := os.WriteFile(filepath.Join(opts.Runtime, "AppRun"), []byte("#!/bin/sh"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := Build("linux", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, err := os.ReadFile(out)
+	if err != nil {
+		t.Fatal(err)
+	}
+	gz, err := gzip.NewReader(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	modes := map[string]int64{}
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		modes[hdr.Name] = hdr.Mode
+	}
+	for _, name := range []string{"jam/AppRun", "jam/jam"} {
+		if modes[name] != 0o755 {
+			t.Errorf("%s mode = %o, want executable", name, modes[name])
+		}
+	}
+	if _, ok := modes["jam/jam.love"]; !ok {
+		t.Errorf("missing jam.love, got %v", modes)
+	}
+}
+
+func TestMissingRuntime(t *testing.T) {
+	opts := setup(t)
+	if _, err := Build("windows", opts); err == nil {
+		t.Error("expected an error without runtime")
+	}
+}
+
+func TestWeb(t *testing.T) {
+	opts := setup(t)
+	opts.Runtime = t.TempDir()
+	for _, name := range []string{"love.js", "love.wasm", "love.worker.js", "index.html"} {
+		if err := os.WriteFile(filepath.Join(opts.Runtime, name), []byte(name), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	out, err := Build("web", opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != WebDir(opts.OutDir, "jam") {
+		t.Errorf("out = %s", out)
+	}
+	read := func(name string) string {
+		data, err := os.ReadFile(filepath.Join(out, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	if got := read("game.data"); got != "GAME" {
+		t.Errorf("game.data = %q", got)
+	}
+	if got := read("love.wasm"); got != "love.wasm" {
+		t.Errorf("love.wasm = %q", got)
+	}
+	if got := read("game.js"); !strings.Contains(got, "Module.INITIAL_MEMORY = 16842752;") {
+		t.Errorf("game.js does not configure the memory:\n%s", got)
+	}
+	if got := read("index.html"); !strings.Contains(got, "<title>jam</title>") || !strings.Contains(got, `src="game.js"`) {
+		t.Errorf("unexpected index.html:\n%s", got)
+	}
+
+	opts.Memory = 1000
+	if _, err := Build("web", opts); err == nil {
+		t.Error("expected an error for memory that is not a multiple of the wasm page size")
+	}
+}
+
+func TestMemorySize(t *testing.T) {
+	if got := memorySize(0); got != minMemory {
+		t.Errorf("memorySize(0) = %d", got)
+	}
+	if got := memorySize(10 * 1024 * 1024); got%wasmPage != 0 || got < minMemory+20*1024*1024 {
+		t.Errorf("memorySize(10MiB) = %d", got)
+	}
+}
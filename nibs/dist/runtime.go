@@ -0,0 +1,249 @@
+// Package dist fuses a .love file with a LÖVE runtime into self-contained distributions.
+package dist
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// file is a single entry of a runtime or a distribution archive.
+// Symlinks (used by the frameworks inside of love.app) have fs.ModeSymlink set and their target as data.
+type file struct {
+	name string
+	mode fs.FileMode
+	data []byte
+}
+
+// readRuntime loads a LÖVE runtime from a zip archive, a tarball or a directory.
+// A single top-level directory (e.g. love-11.5-win64/) is stripped.
+func readRuntime(path string) ([]file, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runtime: %w", err)
+	}
+
+	var files []file
+	switch {
+	case info.IsDir():
+		files, err = readDir(path)
+	case strings.HasSuffix(path, ".zip"):
+		var r *zip.ReadCloser
+		r, err = zip.OpenReader(path)
+		if err != nil {
+			break
+		}
+		defer r.Close()
+		files, err = readZip(&r.Reader)
+	case strings.HasSuffix(path, ".tar.gz"), strings.HasSuffix(path, ".tgz"):
+		files, err = readTarGz(path)
+	default:
+		return nil, fmt.Errorf("unsupported runtime %s: expected a directory, .zip or .tar.gz", path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read runtime %s: %w", path, err)
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("runtime %s is empty", path)
+	}
+	return stripTopLevel(files), nil
+}
+
+func readDir(root string) ([]file, error) {
+	var files []file
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		rel, err := filepath.Rel(root, path)
+		if err != nil {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		f := file{name: filepath.ToSlash(rel), mode: info.Mode() & (fs.ModeSymlink | fs.ModePerm)}
+		if f.mode&fs.ModeSymlink != 0 {
+			target, err := os.Readlink(path)
+			if err != nil {
+				return err
+			}
+			f.data = []byte(target)
+		} else if f.data, err = os.ReadFile(path); err != nil {
+			return err
+		}
+		files = append(files, f)
+		return nil
+	})
+	return files, err
+}
+
+// readZip reads the zip entries directly instead of using fs.FS to keep the stored mode bits
+func readZip(r *zip.Reader) ([]file, error) {
+	var files []file
+	for _, f := range r.File {
+		if f.FileInfo().IsDir() {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return nil, err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return nil, err
+		}
+		mode := f.Mode() & (fs.ModeSymlink | fs.ModePerm)
+		if mode == 0 {
+			mode = 0o644
+		}
+		files = append(files, file{name: path.Clean(f.Name), mode: mode, data: data})
+	}
+	return files, nil
+}
+
+func readTarGz(name string) ([]file, error) {
+	f, err := os.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	gz, err := gzip.NewReader(f)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var files []file
+	tr := tar.NewReader(gz)
+	for {
+		hdr, err := tr.Next()
+		if err == io.EOF {
+			return files, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		f := file{name: path.Clean(hdr.Name), mode: fs.FileMode(hdr.Mode).Perm()}
+		switch hdr.Typeflag {
+		case tar.TypeReg:
+			if f.data, err = io.ReadAll(tr); err != nil {
+				return nil, err
+			}
+		case tar.TypeSymlink:
+			f.mode |= fs.ModeSymlink
+			f.data = []byte(hdr.Linkname)
+		default:
+			continue
+		}
+		files = append(files, f)
+	}
+}
+
+// stripTopLevel removes a directory that contains all files, unless it is an .app bundle
+func stripTopLevel(files []file) []file {
+	top, _, ok := strings.Cut(files[0].name, "/")
+	if !ok || strings.HasSuffix(top, ".app") {
+		return files
+	}
+	for _, f := range files {
+		if !strings.HasPrefix(f.name, top+"/") {
+			return files
+		}
+	}
+	for i := range files {
+		files[i].name = strings.TrimPrefix(files[i].name, top+"/")
+	}
+	return files
+}
+
+// writeZip writes files into a zip archive at path, keeping their mode bits
+func writeZip(path string, files []file) error {
+	sortFiles(files)
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	archive := zip.NewWriter(out)
+	for _, f := range files {
+		hdr := &zip.FileHeader{Name: f.name, Method: zip.Deflate}
+		hdr.SetMode(f.mode)
+		w, err := archive.CreateHeader(hdr)
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(f.data); err != nil {
+			return err
+		}
+	}
+	if err := archive.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+// writeTarGz writes files into a gzip compressed tarball at path, keeping their mode bits
+func writeTarGz(path string, files []file) error {
+	sortFiles(files)
+	out, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer out.Close()
+
+	gz := gzip.NewWriter(out)
+	tw := tar.NewWriter(gz)
+	for _, f := range files {
+		hdr := &tar.Header{
+			Name:     f.name,
+			Mode:     int64(f.mode.Perm()),
+			Size:     int64(len(f.data)),
+			Typeflag: tar.TypeReg,
+		}
+		if f.mode&fs.ModeSymlink != 0 {
+			hdr.Typeflag = tar.TypeSymlink
+			hdr.Linkname = string(f.data)
+			hdr.Size = 0
+		}
+		if err := tw.WriteHeader(hdr); err != nil {
+			return err
+		}
+		if hdr.Typeflag == tar.TypeReg {
+			if _, err := tw.Write(f.data); err != nil {
+				return err
+			}
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	if err := gz.Close(); err != nil {
+		return err
+	}
+	return out.Close()
+}
+
+func sortFiles(files []file) {
+	sort.Slice(files, func(i, j int) bool { return files[i].name < files[j].name })
+}
+
+// outputPath returns the path of a distribution archive and makes sure the output directory exists
+func outputPath(outDir, name string) (string, error) {
+	if err := os.MkdirAll(outDir, 0o755); err != nil {
+		return "", err
+	}
+	return filepath.Join(outDir, name), nil
+}
@@ -0,0 +1,139 @@
+package dist
+
+import (
+	"fmt"
+	"html/template"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	// wasm memory grows in pages of 64KiB
+	wasmPage = 64 * 1024
+	// minMemory is the default of love.js
+	minMemory = 16 * 1024 * 1024
+)
+
+// WebDir returns the folder a web build of the game is written to
+func WebDir(outDir, name string) string {
+	return filepath.Join(outDir, name+"-web")
+}
+
+// web writes a folder that can be served as is: the love.js release, the game as game.data,
+// a game.js loader that mounts it as /game.love and an index.html
+func web(opts Options, game []byte, runtime []file) (string, error) {
+	hasLove := false
+	for _, f := range runtime {
+		if f.name == "love.js" {
+			hasLove = true
+		}
+	}
+	if !hasLove {
+		return "", fmt.Errorf("%s is not a love.js release, love.js is missing", opts.Runtime)
+	}
+
+	memory := opts.Memory
+	if memory == 0 {
+		memory = memorySize(len(game))
+	}
+	if memory%wasmPage != 0 {
+		return "", fmt.Errorf("memory must be a multiple of %d bytes", wasmPage)
+	}
+
+	out := WebDir(opts.OutDir, opts.Name)
+	// start from scratch so files of an older love.js release don't linger
+	if err := os.RemoveAll(out); err != nil {
+		return "", err
+	}
+
+	var index strings.Builder
+	if err := indexTemplate.Execute(&index, opts.Name); err != nil {
+		return "", err
+	}
+	files := []file{
+		{name: "index.html", mode: 0o644, data: []byte(index.String())},
+		{name: "game.data", mode: 0o644, data: game},
+		{name: "game.js", mode: 0o644, data: []byte(fmt.Sprintf(loader, memory))},
+	}
+	for _, f := range runtime {
+		// love.js ships example pages, we bring our own
+		if strings.HasSuffix(f.name, ".html") {
+			continue
+		}
+		files = append(files, f)
+	}
+
+	for _, f := range files {
+		path := filepath.Join(out, filepath.FromSlash(f.name))
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return "", err
+		}
+		if err := os.WriteFile(path, f.data, f.mode.Perm()); err != nil {
+			return "", err
+		}
+	}
+	return out, nil
+}
+
+// memorySize leaves room for the game to be loaded twice (data file and file system) plus the default heap
+func memorySize(gameSize int) int {
+	size := minMemory + 2*gameSize
+	return (size + wasmPage - 1) / wasmPage * wasmPage
+}
+
+// loader fetches game.data and places it at /game.love before LÖVE starts
+const loader = `var Module = typeof Module !== "undefined" ? Module : {};
+Module.INITIAL_MEMORY = %d;
+Module.arguments = ["./game.love"];
+Module.preRun = Module.preRun || [];
+Module.preRun.push(function () {
+	Module.addRunDependency("game.data");
+	fetch("game.data")
+		.then(function (response) {
+			if (!response.ok) {
+				throw new Error("failed to load game.data: " + response.status);
+			}
+			return response.arrayBuffer();
+		})
+		.then(function (data) {
+			Module.FS_createDataFile("/", "game.love", new Uint8Array(data), true, true, true);
+			Module.removeRunDependency("game.data");
+		})
+		.catch(function (err) {
+			Module.printErr(err);
+		});
+});
+`
+
+var indexTemplate = template.Must(template.New("index").Parse(`<!doctype html>
+<html lang="en">
+<head>
+	<meta charset="utf-8">
+	<meta name="viewport" content="width=device-width, initial-scale=1">
+	<title>{{.}}</title>
+	<style>
+		html, body { margin: 0; height: 100%; background: #000; }
+		body { display: flex; align-items: center; justify-content: center; }
+		canvas { outline: none; }
+	</style>
+</head>
+<body>
+	<canvas id="canvas" oncontextmenu="event.preventDefault()"></canvas>
+	<script>
+		var Module = {
+			canvas: document.getElementById("canvas"),
+			print: console.log,
+			printErr: console.error,
+		};
+	</script>
+	<script src="game.js"></script>
+	<script src="love.js"></script>
+	<script>
+		if (typeof Love === "function") {
+			Love(Module);
+		}
+	</script>
+</body>
+</html>
+`))
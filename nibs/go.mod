@@ -3,9 +3,11 @@ module codeberg.org/usysrc/belt/nibs
 go 1.23.2
 
 require (
+	github.com/BurntSushi/toml v1.6.0
 	github.com/fsnotify/fsnotify v1.8.0
 	github.com/go-git/go-git/v5 v5.12.0
 	github.com/spf13/cobra v1.8.1
+	github.com/yuin/gopher-lua v1.1.2
 )
 
 require (
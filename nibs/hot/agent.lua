@@ -0,0 +1,170 @@
+-- nibs hot reload agent, injected into the bundle by `nibs watch --hot`.
+-- It connects to nibs, receives changed files and swaps them into the running game.
+--
+-- Protocol (one request at a time, initiated by nibs):
+--   nibs  -> "<kind> <path> <size>\n" followed by <size> bytes, kind is "lua" or "asset"
+--   agent -> "ok\n" or "error <message>\n"
+local socket = require("socket")
+
+local agent = {
+	port = tonumber(os.getenv("NIBS_HOT_PORT") or ""),
+	images = setmetatable({}, { __mode = "v" }),
+}
+
+-- modules are named like require would name them
+local function moduleName(path)
+	local name = path:gsub("%.lua$", ""):gsub("/init$", ""):gsub("/", ".")
+	return name
+end
+
+-- keep the identity of module tables so that other modules holding a reference see the new functions
+local function swap(old, new)
+	if type(old) ~= "table" or type(new) ~= "table" then
+		return new
+	end
+	for k in pairs(old) do
+		if new[k] == nil then
+			old[k] = nil
+		end
+	end
+	for k, v in pairs(new) do
+		old[k] = v
+	end
+	return old
+end
+
+local function reloadLua(path, data)
+	if path == "main.lua" or path == "conf.lua" then
+		error("cannot hot reload " .. path)
+	end
+	local name = moduleName(path)
+	local chunk, err = loadstring(data, "@" .. path)
+	if not chunk then
+		error(err, 0)
+	end
+	-- modules that were never required will pick up the change on a restart
+	if package.loaded[name] == nil then
+		return
+	end
+	local result = chunk(name)
+	if result == nil then
+		result = true
+	end
+	package.loaded[name] = swap(package.loaded[name], result)
+end
+
+local function reloadAsset(path, data)
+	local file = love.filesystem.newFileData(data, path)
+	local image = agent.images[path]
+	if image then
+		image:replacePixels(love.image.newImageData(file))
+	end
+	if love.hotreload then
+		love.hotreload(path, file)
+	elseif not image then
+		error("no love.hotreload handler for " .. path)
+	end
+end
+
+local function handle(line)
+	local kind, path, size = line:match("^(%S+) (.+) (%d+)$")
+	if not kind then
+		return "error malformed request"
+	end
+	local data, err = agent.conn:receive(tonumber(size))
+	if not data then
+		return "error " .. tostring(err)
+	end
+	local ok, msg = pcall(kind == "lua" and reloadLua or reloadAsset, path, data)
+	if not ok then
+		return "error " .. tostring(msg):gsub("\n", " ")
+	end
+	print("[nibs] reloaded " .. path)
+	return "ok"
+end
+
+function agent.poll()
+	if not agent.conn then
+		return
+	end
+	local line, err = agent.conn:receive("*l")
+	if line then
+		agent.conn:settimeout(1)
+		agent.conn:send(handle(line) .. "\n")
+		agent.conn:settimeout(0)
+	elseif err == "closed" then
+		agent.conn = nil
+	end
+end
+
+function agent.connect()
+	if not agent.port then
+		return
+	end
+	local conn = socket.tcp()
+	conn:settimeout(1)
+	if not conn:connect("127.0.0.1", agent.port) then
+		print("[nibs] hot reload agent could not connect to nibs")
+		return
+	end
+	conn:settimeout(0)
+	agent.conn = conn
+end
+
+-- remember which file every image came from so it can be replaced in place
+local function trackImages()
+	local newImage = love.graphics.newImage
+	love.graphics.newImage = function(source, ...)
+		local image = newImage(source, ...)
+		if type(source) == "string" then
+			agent.images[source] = image
+		end
+		return image
+	end
+end
+
+-- poll once per frame by wrapping the main loop, love.run returns the loop function in LÖVE 11
+local function wrap(run)
+	return function()
+		if love.graphics then
+			trackImages()
+		end
+		agent.connect()
+		local step = run()
+		return function()
+			agent.poll()
+			return step()
+		end
+	end
+end
+
+-- main.lua is loaded after conf.lua and may define its own love.run. love.run is moved behind the
+-- metatable of love, so whatever main.lua assigns is wrapped when LÖVE looks it up.
+local run = love.run
+rawset(love, "run", nil)
+local mt = getmetatable(love) or {}
+local index, newindex = mt.__index, mt.__newindex
+mt.__index = function(t, k)
+	if k == "run" then
+		return run and wrap(run)
+	end
+	if type(index) == "function" then
+		return index(t, k)
+	elseif index then
+		return index[k]
+	end
+end
+mt.__newindex = function(t, k, v)
+	if k == "run" then
+		run = v
+	elseif type(newindex) == "function" then
+		newindex(t, k, v)
+	elseif newindex then
+		newindex[k] = v
+	else
+		rawset(t, k, v)
+	end
+end
+setmetatable(love, mt)
+
+return agent
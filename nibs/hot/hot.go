@@ -0,0 +1,163 @@
+// Package hot talks to the reload agent that runs inside of LÖVE during `nibs watch --hot`.
+package hot
+
+import (
+	"bufio"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net"
+	"path"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Agent is the Lua source of the reload agent
+//
+//go:embed agent.lua
+var Agent []byte
+
+// AgentModule is the module name the agent is bundled as
+const AgentModule = "nibs_hot"
+
+// PortEnv is the environment variable that tells the agent where to connect to
+const PortEnv = "NIBS_HOT_PORT"
+
+// ErrNotConnected is returned when no agent is connected, e.g. because LÖVE is still starting
+var ErrNotConnected = errors.New("hot reload agent is not connected")
+
+// timeout for a single reload, a game that is stuck should rather be restarted
+const timeout = 2 * time.Second
+
+// Server accepts the connection of the agent and sends it changed files
+type Server struct {
+	listener net.Listener
+
+	mu     sync.Mutex
+	conn   net.Conn
+	reader *bufio.Reader
+}
+
+// Listen starts a server on a random local port
+func Listen() (*Server, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return nil, err
+	}
+	s := &Server{listener: l}
+	go s.accept()
+	return s, nil
+}
+
+// Port returns the port the agent has to connect to
+func (s *Server) Port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+// Env returns the environment variable for the LÖVE process
+func (s *Server) Env() string {
+	return fmt.Sprintf("%s=%d", PortEnv, s.Port())
+}
+
+// Close stops the server and disconnects the agent
+func (s *Server) Close() error {
+	s.mu.Lock()
+	s.disconnect()
+	s.mu.Unlock()
+	return s.listener.Close()
+}
+
+// accept replaces the current agent whenever a new LÖVE instance connects
+func (s *Server) accept() {
+	for {
+		conn, err := s.listener.Accept()
+		if err != nil {
+			return
+		}
+		s.mu.Lock()
+		s.disconnect()
+		s.conn = conn
+		s.reader = bufio.NewReader(conn)
+		s.mu.Unlock()
+	}
+}
+
+// Connected reports whether an agent is connected
+func (s *Server) Connected() bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.conn != nil
+}
+
+// Disconnect drops the current agent, e.g. before its LÖVE process is replaced
+func (s *Server) Disconnect() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.disconnect()
+}
+
+func (s *Server) disconnect() {
+	if s.conn != nil {
+		s.conn.Close()
+		s.conn = nil
+		s.reader = nil
+	}
+}
+
+// CanReload reports whether a change to the slash separated path can be applied without a restart
+func CanReload(rel string) bool {
+	switch rel {
+	case "main.lua", "conf.lua":
+		return false
+	}
+	return true
+}
+
+// Reload sends the new content of the file at the slash separated project relative path to the agent.
+// Any error means the change could not be applied and LÖVE should be restarted.
+func (s *Server) Reload(rel string, data []byte) error {
+	if !CanReload(rel) {
+		return fmt.Errorf("%s cannot be hot reloaded", rel)
+	}
+	kind := "asset"
+	if path.Ext(rel) == ".lua" {
+		kind = "lua"
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.conn == nil {
+		return ErrNotConnected
+	}
+
+	s.conn.SetDeadline(time.Now().Add(timeout))
+	_, err := fmt.Fprintf(s.conn, "%s %s %d\n", kind, rel, len(data))
+	if err == nil {
+		_, err = s.conn.Write(data)
+	}
+	var reply string
+	if err == nil {
+		reply, err = s.reader.ReadString('\n')
+	}
+	if err != nil {
+		// the agent is gone or stuck, a restart will bring up a new one
+		s.disconnect()
+		return err
+	}
+
+	reply = strings.TrimSpace(reply)
+	if reply != "ok" {
+		return errors.New(strings.TrimPrefix(reply, "error "))
+	}
+	return nil
+}
+
+// Bundle returns the extra files that have to be added to the bundle to load the agent.
+// conf.lua is loaded before main.lua so the agent is required from there, without changing line numbers.
+func Bundle(conf []byte) map[string][]byte {
+	return map[string][]byte{
+		AgentModule + ".lua": Agent,
+		"conf.lua":           append([]byte(`require("`+AgentModule+`") `), conf...),
+	}
+}
@@ -0,0 +1,159 @@
+package hot
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+// fakeAgent connects to the server and answers every request with reply
+func fakeAgent(t *testing.T, s *Server, reply func(kind, path string, data []byte) string) {
+	t.Helper()
+	conn, err := net.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", s.Port()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		r := bufio.NewReader(conn)
+		for {
+			line, err := r.ReadString('\n')
+			if err != nil {
+				return
+			}
+			var kind, path string
+			var size int
+			fmt.Sscanf(line, "%s %s %d", &kind, &path, &size)
+			data := make([]byte, size)
+			if _, err := io.ReadFull(r, data); err != nil {
+				return
+			}
+			fmt.Fprintln(conn, reply(kind, path, data))
+		}
+	}()
+
+	// wait until the server accepted the connection
+	for i := 0; i < 100; i++ {
+		if s.Connected() {
+			return
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	t.Fatal("agent did not connect")
+}
+
+func TestReload(t *testing.T) {
+	s, err := Listen()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer s.Close()
+
+	if err := s.Reload("player.lua", nil); !errors.Is(err, ErrNotConnected) {
+		t.Fatalf("expected ErrNotConnected, got %v", err)
+	}
+
+	var got []string
+	fakeAgent(t, s, func(kind, path string, data []byte) string {
+		got = append(got, kind+" "+path+" "+string(data))
+		if path == "broken.lua" {
+			return "error broken.lua:1: unexpected symbol"
+		}
+		return "ok"
+	})
+
+	if err := s.Reload("lib/player.lua", []byte("return {}")); err != nil {
+		t.Errorf("Reload: %v", err)
+	}
+	if err := s.Reload("gfx/hero.png", []byte("PNG")); err != nil {
+		t.Errorf("Reload: %v", err)
+	}
+	err = s.Reload("broken.lua", []byte("x ="))
+	if err == nil || !strings.Contains(err.Error(), "unexpected symbol") {
+		t.Errorf("expected the agent error, got %v", err)
+	}
+	if err := s.Reload("main.lua", []byte("")); err == nil {
+		t.Errorf("main.lua must not be hot reloaded")
+	}
+
+	want := []string{"lua lib/player.lua return {}", "asset gfx/hero.png PNG", "lua broken.lua x ="}
+	if strings.Join(got, "|") != strings.Join(want, "|") {
+		t.Errorf("agent received %q, want %q", got, want)
+	}
+}
+
+func TestBundle(t *testing.T) {
+	files := Bundle([]byte("function love.conf(t) end"))
+	if string(files["conf.lua"]) != `require("nibs_hot") function love.conf(t) end` {
+		t.Errorf("conf.lua = %q", files["conf.lua"])
+	}
+	if len(files[AgentModule+".lua"]) == 0 {
+		t.Errorf("agent is missing")
+	}
+}
+
+// agentLoop loads the agent like conf.lua does, runs main and then starts the main loop like LÖVE's boot does.
+// It returns the port the agent connected to, 0 if it did not connect.
+func agentLoop(t *testing.T, main string) int {
+	t.Helper()
+	t.Setenv(PortEnv, "4711")
+	L := lua.NewState()
+	defer L.Close()
+	setup := `
+love = { run = function() return function() end end }
+package.preload.socket = function()
+	return { tcp = function()
+		return {
+			settimeout = function() end,
+			connect = function(self, host, port) connected = port return 1 end,
+			receive = function() return nil, "timeout" end,
+		}
+	end }
+end
+connected = 0`
+	if err := L.DoString(setup); err != nil {
+		t.Fatal(err)
+	}
+	agent, err := L.LoadString(string(Agent))
+	if err != nil {
+		t.Fatal(err)
+	}
+	L.Push(agent)
+	if err := L.PCall(0, 0, nil); err != nil {
+		t.Fatalf("loading the agent: %v", err)
+	}
+	if err := L.DoString(main); err != nil {
+		t.Fatalf("main.lua: %v", err)
+	}
+	if err := L.DoString("local loop = love.run() loop() loop() assert(frames == nil or frames == 2, \"the main loop did not run\")"); err != nil {
+		t.Fatalf("love.run: %v", err)
+	}
+	return int(lua.LVAsNumber(L.GetGlobal("connected")))
+}
+
+func TestAgentHooksLoveRun(t *testing.T) {
+	if port := agentLoop(t, ""); port != 4711 {
+		t.Errorf("agent with the default love.run connected to %d", port)
+	}
+
+	// a love.run from main.lua is wrapped as well and still runs
+	main := `
+frames = 0
+function love.run()
+	return function() frames = frames + 1 end
+end
+function love.update(dt) end
+assert(rawget(love, "update"), "other callbacks are stored as usual")
+`
+	if port := agentLoop(t, main); port != 4711 {
+		t.Errorf("agent with the love.run of main.lua connected to %d", port)
+	}
+}
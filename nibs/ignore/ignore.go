@@ -0,0 +1,117 @@
+// Package ignore decides which files of a project end up in a bundle, using gitignore style patterns.
+package ignore
+
+import (
+	"bufio"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/go-git/go-git/v5/plumbing/format/gitignore"
+)
+
+// FileName is the name of the ignore file in the source folder
+const FileName = ".nibsignore"
+
+// Defaults are always excluded from bundles, they can be re-included with "!pattern"
+var Defaults = []string{
+	".git/",
+	".DS_Store",
+	"*~",
+	"*.swp",
+	"*.love",
+	".nibs-stage-*/",
+	"/" + FileName,
+	"/nibs.toml",
+	"/nibs.lock",
+	"/dist/",
+	"/build/",
+}
+
+// Rules matches project relative paths against the include and exclude patterns
+type Rules struct {
+	include  gitignore.Matcher
+	exclude  gitignore.Matcher
+	excludes []string
+}
+
+// New creates rules from include and exclude patterns.
+// If include is empty every file that is not excluded is included.
+// Later exclude patterns take precedence over earlier ones.
+func New(include, exclude []string) *Rules {
+	r := &Rules{}
+	r.Exclude(exclude...)
+	if len(include) > 0 {
+		r.include = matcher(include)
+	}
+	return r
+}
+
+// Load creates rules for the source folder dir from the defaults, the given exclude patterns and the .nibsignore file
+func Load(dir string, include, exclude []string) (*Rules, error) {
+	patterns := append([]string{}, Defaults...)
+	patterns = append(patterns, exclude...)
+
+	fromFile, err := ReadFile(filepath.Join(dir, FileName))
+	if err != nil {
+		return nil, err
+	}
+	patterns = append(patterns, fromFile...)
+	return New(include, patterns), nil
+}
+
+// ReadFile reads the patterns from an ignore file, skipping empty lines and comments.
+// A missing file has no patterns.
+func ReadFile(path string) ([]string, error) {
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var patterns []string
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		patterns = append(patterns, line)
+	}
+	return patterns, scanner.Err()
+}
+
+// Exclude adds patterns with the highest precedence, e.g. for the output file
+func (r *Rules) Exclude(patterns ...string) {
+	r.excludes = append(r.excludes, patterns...)
+	r.exclude = matcher(r.excludes)
+}
+
+// Ignored reports whether the slash or os separated path relative to the source folder is left out of the bundle.
+// Directories are only checked against the exclude patterns so that included files inside of them can still be found.
+func (r *Rules) Ignored(path string, isDir bool) bool {
+	parts := split(path)
+	if r.exclude.Match(parts, isDir) {
+		return true
+	}
+	if isDir || r.include == nil {
+		return false
+	}
+	return !r.include.Match(parts, isDir)
+}
+
+func matcher(patterns []string) gitignore.Matcher {
+	ps := make([]gitignore.Pattern, 0, len(patterns))
+	for _, p := range patterns {
+		ps = append(ps, gitignore.ParsePattern(p, nil))
+	}
+	return gitignore.NewMatcher(ps)
+}
+
+func split(path string) []string {
+	return strings.Split(filepath.ToSlash(filepath.Clean(path)), "/")
+}
@@ -0,0 +1,75 @@
+package ignore
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestIgnored(t *testing.T) {
+	dir := t.TempDir()
+	nibsignore := "# comment\n\n*.psd\n/docs/\n!docs/keep.md\n"
+	if err := os.WriteFile(filepath.Join(dir, FileName), []byte(nibsignore), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	rules, err := Load(dir, nil, []string{"secrets.lua"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	rules.Exclude("/game.love")
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"main.lua", false, false},
+		{"my~stuff/player.lua", false, false},
+		{"my.github/readme.md", false, false},
+		{"backup.lua~", false, true},
+		{".swap.lua.swp", false, true},
+		{".git", true, true},
+		{"lib/.git", true, true},
+		{"art/hero.psd", false, true},
+		{"art/hero.png", false, false},
+		{"docs", true, true},
+		{"lib/docs", true, false},
+		{"secrets.lua", false, true},
+		{"lib/secrets.lua", false, true},
+		{"game.love", false, true},
+		{"lib/game.love", false, true},
+		{"lib/game.lua", false, false},
+		{"dist", true, true},
+		{"nibs.toml", false, true},
+		{FileName, false, true},
+	}
+	for _, tt := range tests {
+		if got := rules.Ignored(tt.path, tt.isDir); got != tt.ignored {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.ignored)
+		}
+	}
+}
+
+func TestInclude(t *testing.T) {
+	rules := New([]string{"*.lua", "assets/"}, []string{"*.wav"})
+
+	tests := []struct {
+		path    string
+		isDir   bool
+		ignored bool
+	}{
+		{"main.lua", false, false},
+		{"lib/util.lua", false, false},
+		{"assets/hero.png", false, false},
+		{"assets/jump.wav", false, true},
+		{"README.md", false, true},
+		// directories are walked so included files inside can be found
+		{"lib", true, false},
+	}
+	for _, tt := range tests {
+		if got := rules.Ignored(tt.path, tt.isDir); got != tt.ignored {
+			t.Errorf("Ignored(%q, %v) = %v, want %v", tt.path, tt.isDir, got, tt.ignored)
+		}
+	}
+}
@@ -0,0 +1,33 @@
+// Package luasrc has the helpers to scan Lua source that the checks and the pipeline share.
+package luasrc
+
+import (
+	"bytes"
+	"strings"
+)
+
+// LongBracket reports whether a long bracket like [[ or [==[ starts at i and returns its level
+func LongBracket(src []byte, i int) (int, bool) {
+	if i >= len(src) || src[i] != '[' {
+		return 0, false
+	}
+	level := 0
+	for i++; i < len(src) && src[i] == '='; i++ {
+		level++
+	}
+	if i >= len(src) || src[i] != '[' {
+		return 0, false
+	}
+	return level, true
+}
+
+// LongEnd returns the index after the closing bracket of the long string or comment starting at i,
+// or the end of src if it is not closed
+func LongEnd(src []byte, i, level int) int {
+	closing := "]" + strings.Repeat("=", level) + "]"
+	end := bytes.Index(src[i:], []byte(closing))
+	if end < 0 {
+		return len(src)
+	}
+	return i + end + len(closing)
+}
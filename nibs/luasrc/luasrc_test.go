@@ -0,0 +1,30 @@
+package luasrc
+
+import "testing"
+
+func TestLongBracket(t *testing.T) {
+	tests := []struct {
+		src   string
+		level int
+		ok    bool
+		end   int
+	}{
+		{"[[a]]", 0, true, 5},
+		{"[==[a]]b]==]c", 2, true, 12},
+		{"[=[a", 1, true, 4},
+		{"[a]", 0, false, 0},
+		{"[==", 0, false, 0},
+		{"x", 0, false, 0},
+	}
+	for _, tt := range tests {
+		level, ok := LongBracket([]byte(tt.src), 0)
+		if level != tt.level || ok != tt.ok {
+			t.Errorf("LongBracket(%q) = %d, %v, want %d, %v", tt.src, level, ok, tt.level, tt.ok)
+		}
+		if ok {
+			if end := LongEnd([]byte(tt.src), 0, level); end != tt.end {
+				t.Errorf("LongEnd(%q) = %d, want %d", tt.src, end, tt.end)
+			}
+		}
+	}
+}
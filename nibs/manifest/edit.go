@@ -0,0 +1,198 @@
+package manifest
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"codeberg.org/usysrc/belt/nibs/battery"
+	"github.com/BurntSushi/toml"
+)
+
+// nibs.toml is written by hand, so batteries are added and removed by editing
+// only their own [batteries.<name>] table. Comments, formatting and all other
+// settings of the file are kept as they are.
+
+// SetBattery writes the [batteries.<name>] table of the manifest in dir.
+// An existing table of the battery is replaced in place, otherwise the table is appended.
+func SetBattery(dir, name string, b battery.Battery) error {
+	path := filepath.Join(dir, FileName)
+	data, err := os.ReadFile(path)
+	if err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+
+	var table bytes.Buffer
+	fmt.Fprintf(&table, "[batteries.%s]\n", tomlKey(name))
+	enc := toml.NewEncoder(&table)
+	enc.Indent = ""
+	if err := enc.Encode(b); err != nil {
+		return err
+	}
+
+	lines, at := cutBattery(splitLines(data), name)
+	if at < 0 {
+		at = len(lines)
+	}
+	out := strings.Join(lines[:at], "")
+	if at > 0 && !isBlank(lines[at-1]) {
+		out += "\n"
+	}
+	out += table.String()
+	if at < len(lines) && !isBlank(lines[at]) {
+		out += "\n"
+	}
+	out += strings.Join(lines[at:], "")
+	return writeEdit(path, name, []byte(out), true)
+}
+
+// RemoveBattery deletes the [batteries.<name>] table from the manifest in dir
+func RemoveBattery(dir, name string) error {
+	path := filepath.Join(dir, FileName)
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	lines, _ := cutBattery(splitLines(data), name)
+	return writeEdit(path, name, []byte(strings.Join(lines, "")), false)
+}
+
+// writeEdit checks that the edited manifest still parses and contains the
+// battery only if it should, before replacing the file at path
+func writeEdit(path, name string, data []byte, want bool) error {
+	var m Manifest
+	if _, err := toml.Decode(string(data), &m); err != nil {
+		return fmt.Errorf("editing battery %s: %w", name, err)
+	}
+	if _, ok := m.Batteries[name]; ok != want {
+		return fmt.Errorf("battery %s is not defined in its own [batteries.%s] table and has to be edited by hand", name, tomlKey(name))
+	}
+	return os.WriteFile(path, data, 0o644)
+}
+
+// cutBattery removes the table of the battery and its subtables from lines.
+// It returns the remaining lines and the index the first table was removed at, or -1.
+// Comments and blank lines directly above the next table stay with that table.
+func cutBattery(lines []string, name string) ([]string, int) {
+	out := make([]string, 0, len(lines))
+	at := -1
+	for i := 0; i < len(lines); {
+		key, ok := tableHeader(lines[i])
+		if !ok || len(key) < 2 || key[0] != "batteries" || key[1] != name {
+			out = append(out, lines[i])
+			i++
+			continue
+		}
+		end := i + 1
+		for end < len(lines) {
+			if _, ok := tableHeader(lines[end]); ok {
+				break
+			}
+			end++
+		}
+		if end < len(lines) {
+			for end > i+1 && isTrivia(lines[end-1]) {
+				end--
+			}
+		}
+		// drop the blank lines that separated the table from the previous one
+		for len(out) > 0 && isBlank(out[len(out)-1]) && (end == len(lines) || isBlank(lines[end])) {
+			out = out[:len(out)-1]
+		}
+		if at < 0 || at > len(out) {
+			at = len(out)
+		}
+		i = end
+	}
+	return out, at
+}
+
+func splitLines(data []byte) []string {
+	if len(data) == 0 {
+		return nil
+	}
+	lines := strings.SplitAfter(string(data), "\n")
+	if lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	} else {
+		lines[len(lines)-1] += "\n"
+	}
+	return lines
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func isTrivia(line string) bool {
+	line = strings.TrimSpace(line)
+	return line == "" || strings.HasPrefix(line, "#")
+}
+
+// tableHeader parses a [table] or [[array]] header line into its key parts
+func tableHeader(line string) ([]string, bool) {
+	s := strings.TrimSpace(line)
+	if !strings.HasPrefix(s, "[") {
+		return nil, false
+	}
+	s = strings.TrimPrefix(strings.TrimPrefix(s, "["), "[")
+	var key []string
+	for {
+		s = strings.TrimLeft(s, " \t")
+		var part string
+		switch {
+		case strings.HasPrefix(s, `"`):
+			end := 1
+			for end < len(s) && s[end] != '"' {
+				if s[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			if end >= len(s) {
+				return nil, false
+			}
+			unquoted, err := strconv.Unquote(s[:end+1])
+			if err != nil {
+				return nil, false
+			}
+			part, s = unquoted, s[end+1:]
+		case strings.HasPrefix(s, "'"):
+			end := strings.IndexByte(s[1:], '\'')
+			if end < 0 {
+				return nil, false
+			}
+			part, s = s[1:end+1], s[end+2:]
+		default:
+			bare := bareKey.FindString(s)
+			if bare == "" {
+				return nil, false
+			}
+			part, s = bare, s[len(bare):]
+		}
+		key = append(key, part)
+		s = strings.TrimLeft(s, " \t")
+		if strings.HasPrefix(s, "]") {
+			return key, true
+		}
+		if !strings.HasPrefix(s, ".") {
+			return nil, false
+		}
+		s = s[1:]
+	}
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// tomlKey quotes name if it is not a valid bare key
+func tomlKey(name string) string {
+	if name != "" && bareKey.FindString(name) == name {
+		return name
+	}
+	return strconv.Quote(name)
+}
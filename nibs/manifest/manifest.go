@@ -0,0 +1,166 @@
+// Package manifest reads and writes the project manifest (nibs.toml) and lock file (nibs.lock).
+package manifest
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"codeberg.org/usysrc/belt/nibs/battery"
+	"codeberg.org/usysrc/belt/nibs/pipeline"
+	"github.com/BurntSushi/toml"
+)
+
+const (
+	FileName     = "nibs.toml"
+	LockFileName = "nibs.lock"
+)
+
+// Manifest is the declarative description of a project
+type Manifest struct {
+	Project   Project                    `toml:"project,omitempty"`
+	Love      Love                       `toml:"love,omitempty"`
+	Bundle    Bundle                     `toml:"bundle,omitempty"`
+	Check     Check                      `toml:"check,omitempty"`
+	Hooks     Hooks                      `toml:"hooks,omitempty"`
+	Pipeline  pipeline.Config            `toml:"pipeline,omitempty"`
+	Watch     Watch                      `toml:"watch,omitempty"`
+	Batteries map[string]battery.Battery `toml:"batteries,omitempty"`
+}
+
+// Project describes the layout of the project, paths are relative to the directory of nibs.toml
+type Project struct {
+	// Source is the folder with main.lua, the directory of nibs.toml if empty
+	Source string `toml:"source,omitempty"`
+	// Output is the .love file, <directory name>.love if empty
+	Output string `toml:"output,omitempty"`
+}
+
+// Love configures how LÖVE is started
+type Love struct {
+	// Path to the love binary, "love" from the PATH if empty
+	Path string `toml:"path,omitempty"`
+	// Args are passed to love after the game
+	Args []string `toml:"args,omitempty"`
+}
+
+// Bundle configures which files end up in the .love file
+type Bundle struct {
+	// Include limits the bundle to files matching these gitignore style patterns
+	Include []string `toml:"include,omitempty"`
+	// Exclude adds gitignore style patterns to the ones from .nibsignore
+	Exclude []string `toml:"exclude,omitempty"`
+	// Level is the deflate level from 1 to 9, -1 for the default or 0 to store every file
+	Level *int `toml:"level,omitempty"`
+	// Store lists extensions that are stored without compression, instead of the defaults
+	Store []string `toml:"store,omitempty"`
+	// NoBuildInfo leaves out the generated nibs_build.lua
+	NoBuildInfo bool `toml:"no_build_info,omitempty"`
+}
+
+// Check configures the static checks of the Lua sources
+type Check struct {
+	// Globals may be assigned without a warning
+	Globals []string `toml:"globals,omitempty"`
+}
+
+// Hooks are shell commands that run in the project directory around bundling.
+// They get the paths of the project, the source folder and the bundle as NIBS_PROJECT_DIR, NIBS_SOURCE_DIR and NIBS_OUTPUT.
+type Hooks struct {
+	// PreBundle runs before the files are collected, e.g. to export sprites
+	PreBundle []string `toml:"pre_bundle,omitempty"`
+	// PostBundle runs once the bundle was written
+	PostBundle []string `toml:"post_bundle,omitempty"`
+}
+
+// Watch configures nibs watch
+type Watch struct {
+	// Extensions of the files that trigger a rebuild
+	Extensions []string `toml:"extensions,omitempty"`
+}
+
+// Lock records the exact versions of the vendored batteries
+type Lock struct {
+	Batteries map[string]battery.Pin `toml:"batteries,omitempty"`
+}
+
+// Load reads the manifest from dir. A missing manifest results in an empty one.
+func Load(dir string) (*Manifest, error) {
+	m := &Manifest{}
+	if err := decode(filepath.Join(dir, FileName), m); err != nil {
+		return nil, err
+	}
+	if m.Batteries == nil {
+		m.Batteries = map[string]battery.Battery{}
+	}
+	for name, b := range m.Batteries {
+		b.Name = name
+		m.Batteries[name] = b
+	}
+	return m, nil
+}
+
+// Find returns the directory of the nearest nibs.toml, searching upwards from dir.
+// Without a manifest the absolute path of dir is returned.
+func Find(dir string) (string, error) {
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	for current := abs; ; {
+		info, err := os.Stat(filepath.Join(current, FileName))
+		if err == nil && !info.IsDir() {
+			return current, nil
+		}
+		parent := filepath.Dir(current)
+		if parent == current {
+			return abs, nil
+		}
+		current = parent
+	}
+}
+
+// Names returns the sorted names of all batteries in the manifest
+func (m *Manifest) Names() []string {
+	names := make([]string, 0, len(m.Batteries))
+	for name := range m.Batteries {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// LoadLock reads the lock file from dir. A missing lock file results in an empty one.
+func LoadLock(dir string) (*Lock, error) {
+	l := &Lock{}
+	if err := decode(filepath.Join(dir, LockFileName), l); err != nil {
+		return nil, err
+	}
+	if l.Batteries == nil {
+		l.Batteries = map[string]battery.Pin{}
+	}
+	return l, nil
+}
+
+// Save writes the lock file to dir
+func (l *Lock) Save(dir string) error {
+	return encode(filepath.Join(dir, LockFileName), l)
+}
+
+func decode(path string, v any) error {
+	_, err := toml.DecodeFile(path, v)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
+
+func encode(path string, v any) error {
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	return toml.NewEncoder(f).Encode(v)
+}
@@ -0,0 +1,179 @@
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"codeberg.org/usysrc/belt/nibs/battery"
+)
+
+func TestManifestRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	m, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load on empty dir: %v", err)
+	}
+	if len(m.Batteries) != 0 {
+		t.Fatalf("expected empty manifest, got %v", m.Batteries)
+	}
+
+	m.Batteries["hump"] = battery.Battery{Name: "hump", Source: battery.SourceGit, URL: "https://example.com/hump.git", Ref: "v1", Target: "hump"}
+	m.Batteries["pico"] = battery.Battery{Name: "pico", Source: battery.SourceFile, URL: "https://example.com/pico.lua", Target: "pico.lua"}
+	for _, name := range []string{"pico", "hump"} {
+		if err := SetBattery(dir, name, m.Batteries[name]); err != nil {
+			t.Fatalf("SetBattery(%s): %v", name, err)
+		}
+	}
+
+	got, err := Load(dir)
+	if err != nil {
+		t.Fatalf("Load: %v", err)
+	}
+	if !reflect.DeepEqual(got.Batteries, m.Batteries) {
+		t.Errorf("got %+v, want %+v", got.Batteries, m.Batteries)
+	}
+	if names := got.Names(); !reflect.DeepEqual(names, []string{"hump", "pico"}) {
+		t.Errorf("Names() = %v", names)
+	}
+}
+
+func TestLockRoundTrip(t *testing.T) {
+	dir := t.TempDir()
+
+	l, err := LoadLock(dir)
+	if err != nil {
+		t.Fatalf("LoadLock on empty dir: %v", err)
+	}
+	l.Batteries["hump"] = battery.Pin{Commit: "08937cc0ecf72d1a964a8de6cd552c5e136bf0d4"}
+	l.Batteries["pico"] = battery.Pin{SHA256: "e3b0c44298fc1c149afbf4c8996fb92427ae41e4649b934ca495991b7852b855"}
+	if err := l.Save(dir); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+
+	got, err := LoadLock(dir)
+	if err != nil {
+		t.Fatalf("LoadLock: %v", err)
+	}
+	if !reflect.DeepEqual(got.Batteries, l.Batteries) {
+		t.Errorf("got %+v, want %+v", got.Batteries, l.Batteries)
+	}
+}
+
+func TestFind(t *testing.T) {
+	root := t.TempDir()
+	nested := filepath.Join(root, "src", "lib")
+	if err := os.MkdirAll(nested, 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	// without a manifest the directory itself is the project
+	if dir, err := Find(nested); err != nil || dir != nested {
+		t.Errorf("Find without manifest = %q, %v", dir, err)
+	}
+
+	if err := os.WriteFile(filepath.Join(root, FileName), []byte("[project]\nsource = \"src\"\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	for _, start := range []string{root, nested} {
+		if dir, err := Find(start); err != nil || dir != root {
+			t.Errorf("Find(%s) = %q, %v, want %s", start, dir, err, root)
+		}
+	}
+
+	m, err := Load(root)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if m.Project.Source != "src" {
+		t.Errorf("source = %q", m.Project.Source)
+	}
+}
+
+func TestEditBatteries(t *testing.T) {
+	dir := t.TempDir()
+	const original = `# my game
+[project]
+name = "demo"   # shown in the title
+future = "kept"
+
+[batteries.hump]
+# pinned for the camera module
+source = "git"
+url = "https://example.com/hump.git"
+target = "lib/hump"
+
+[batteries.hump.extra]
+note = "subtable"
+
+# the renderer
+[love]
+version = "11.5"
+`
+	path := filepath.Join(dir, FileName)
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	pico := battery.Battery{Source: battery.SourceFile, URL: "https://example.com/pico.lua", Target: "pico.lua"}
+	if err := SetBattery(dir, "pico", pico); err != nil {
+		t.Fatalf("SetBattery: %v", err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := original + `
+[batteries.pico]
+source = "file"
+url = "https://example.com/pico.lua"
+target = "pico.lua"
+`
+	if string(data) != want {
+		t.Errorf("after SetBattery:\n%s\nwant:\n%s", data, want)
+	}
+
+	if err := RemoveBattery(dir, "hump"); err != nil {
+		t.Fatalf("RemoveBattery: %v", err)
+	}
+	if err := RemoveBattery(dir, "pico"); err != nil {
+		t.Fatalf("RemoveBattery: %v", err)
+	}
+	data, err = os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want = `# my game
+[project]
+name = "demo"   # shown in the title
+future = "kept"
+
+# the renderer
+[love]
+version = "11.5"
+`
+	if string(data) != want {
+		t.Errorf("after RemoveBattery:\n%s\nwant:\n%s", data, want)
+	}
+}
+
+func TestEditInlineBattery(t *testing.T) {
+	dir := t.TempDir()
+	const original = "[batteries]\npico = { source = \"file\", url = \"https://example.com/pico.lua\", target = \"pico.lua\" }\n"
+	path := filepath.Join(dir, FileName)
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := RemoveBattery(dir, "pico"); err == nil {
+		t.Error("expected an error for a battery that is not in its own table")
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if string(data) != original {
+		t.Errorf("manifest was changed:\n%s", data)
+	}
+}
@@ -0,0 +1,112 @@
+package pipeline
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/draw"
+	"image/png"
+	"math"
+	"sort"
+	"strings"
+)
+
+// sprite is a single image of an atlas and its place in it
+type sprite struct {
+	file  string
+	name  string
+	image image.Image
+	x, y  int
+}
+
+// build packs the sprites of the atlas and returns the PNG, the Lua lookup table and the files that were packed
+func (a Atlas) build(dir string, files []string) ([]byte, []byte, []string, error) {
+	var sprites []*sprite
+	var packed []string
+	for _, file := range files {
+		if !a.contains(file) {
+			continue
+		}
+		data, err := readFile(dir, file)
+		if err != nil {
+			return nil, nil, nil, err
+		}
+		img, err := png.Decode(bytes.NewReader(data))
+		if err != nil {
+			return nil, nil, nil, fmt.Errorf("%s: %w", file, err)
+		}
+		name := strings.TrimPrefix(file, strings.TrimSuffix(a.Dir, "/")+"/")
+		name = name[:len(name)-len(".png")]
+		sprites = append(sprites, &sprite{file: file, name: name, image: img})
+		packed = append(packed, file)
+	}
+	if len(sprites) == 0 {
+		return nil, nil, nil, fmt.Errorf("atlas %s: no PNG files in %s", a.output(), a.Dir)
+	}
+
+	width, height := pack(sprites, a.Padding)
+	atlas := image.NewNRGBA(image.Rect(0, 0, width, height))
+	for _, s := range sprites {
+		b := s.image.Bounds()
+		draw.Draw(atlas, image.Rect(s.x, s.y, s.x+b.Dx(), s.y+b.Dy()), s.image, b.Min, draw.Src)
+	}
+	var out bytes.Buffer
+	if err := png.Encode(&out, atlas); err != nil {
+		return nil, nil, nil, err
+	}
+	return out.Bytes(), a.lua(sprites, width, height), packed, nil
+}
+
+// pack places the sprites on shelves, tallest first, and returns the size of the atlas.
+// The width is the smallest power of two that fits the area of all sprites when packed tightly.
+func pack(sprites []*sprite, padding int) (int, int) {
+	sort.Slice(sprites, func(i, j int) bool {
+		hi, hj := sprites[i].image.Bounds().Dy(), sprites[j].image.Bounds().Dy()
+		if hi != hj {
+			return hi > hj
+		}
+		return sprites[i].name < sprites[j].name
+	})
+
+	area, widest := 0, 0
+	for _, s := range sprites {
+		b := s.image.Bounds()
+		area += (b.Dx() + 2*padding) * (b.Dy() + 2*padding)
+		widest = max(widest, b.Dx()+2*padding)
+	}
+	width := 1
+	for width < widest || width < int(math.Ceil(math.Sqrt(float64(area)))) {
+		width *= 2
+	}
+
+	x, y, shelf := 0, 0, 0
+	for _, s := range sprites {
+		w, h := s.image.Bounds().Dx()+2*padding, s.image.Bounds().Dy()+2*padding
+		if x+w > width {
+			x, y, shelf = 0, y+shelf, 0
+		}
+		s.x, s.y = x+padding, y+padding
+		x += w
+		shelf = max(shelf, h)
+	}
+	return width, y + shelf
+}
+
+// lua returns the lookup table of the atlas, sprites are named by their path inside of the folder
+func (a Atlas) lua(sprites []*sprite, width, height int) []byte {
+	sorted := append([]*sprite(nil), sprites...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].name < sorted[j].name })
+
+	var b strings.Builder
+	b.WriteString("-- generated by nibs\nreturn {\n")
+	fmt.Fprintf(&b, "\timage = %q,\n", a.output()+".png")
+	fmt.Fprintf(&b, "\twidth = %d,\n", width)
+	fmt.Fprintf(&b, "\theight = %d,\n", height)
+	b.WriteString("\tsprites = {\n")
+	for _, s := range sorted {
+		bounds := s.image.Bounds()
+		fmt.Fprintf(&b, "\t\t[%q] = { x = %d, y = %d, w = %d, h = %d },\n", s.name, s.x, s.y, bounds.Dx(), bounds.Dy())
+	}
+	b.WriteString("\t},\n}\n")
+	return []byte(b.String())
+}
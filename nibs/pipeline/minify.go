@@ -0,0 +1,88 @@
+package pipeline
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+
+	"codeberg.org/usysrc/belt/nibs/luasrc"
+)
+
+func readFile(dir, file string) ([]byte, error) {
+	return os.ReadFile(filepath.Join(dir, filepath.FromSlash(file)))
+}
+
+// Minify removes comments, indentation and repeated spaces from Lua source.
+// Newlines are kept, so error messages of the game still point at the right line.
+func Minify(src []byte) []byte {
+	var out bytes.Buffer
+	// whitespace and comments are collected and written as a single separator before the next token
+	newlines, space := 0, false
+	flush := func() {
+		switch {
+		case newlines > 0:
+			out.Write(bytes.Repeat([]byte("\n"), newlines))
+		case space && out.Len() > 0:
+			out.WriteByte(' ')
+		}
+		newlines, space = 0, false
+	}
+
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '\n':
+			newlines++
+			i++
+		case c == ' ' || c == '\t' || c == '\r' || c == '\f' || c == '\v':
+			space = true
+			i++
+		case c == '-' && i+1 < len(src) && src[i+1] == '-':
+			end := len(src)
+			if level, ok := luasrc.LongBracket(src, i+2); ok {
+				end = luasrc.LongEnd(src, i+2, level)
+			} else if n := bytes.IndexByte(src[i:], '\n'); n >= 0 {
+				// the newline itself is kept as separator
+				end = i + n
+			}
+			newlines += bytes.Count(src[i:end], []byte("\n"))
+			space = true
+			i = end
+		case c == '[':
+			flush()
+			end := i + 1
+			if level, ok := luasrc.LongBracket(src, i); ok {
+				end = luasrc.LongEnd(src, i, level)
+			}
+			out.Write(src[i:end])
+			i = end
+		case c == '"' || c == '\'':
+			flush()
+			end := closeString(src, i)
+			out.Write(src[i:end])
+			i = end
+		default:
+			flush()
+			out.WriteByte(c)
+			i++
+		}
+	}
+	// keep the newlines at the end of the file, but no trailing spaces
+	space = false
+	flush()
+	return out.Bytes()
+}
+
+// closeString returns the index after the closing quote of the string at i
+func closeString(src []byte, i int) int {
+	quote := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch src[j] {
+		case '\\':
+			j++
+		case quote, '\n':
+			return j + 1
+		}
+	}
+	return len(src)
+}
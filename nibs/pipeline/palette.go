@@ -0,0 +1,102 @@
+package pipeline
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// rgb is an RGB color with 8 bits per channel
+type rgb [3]uint8
+
+// convertPalette reads a palette file of the project and returns it as Lua module
+func convertPalette(dir, file string) ([]byte, error) {
+	data, err := readFile(dir, file)
+	if err != nil {
+		return nil, err
+	}
+	colors, err := parsePalette(path.Ext(file), data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", file, err)
+	}
+	return paletteLua(colors), nil
+}
+
+// parsePalette reads a JASC .pal or a .hex palette (one rrggbb per line, as exported by lospec)
+func parsePalette(ext string, data []byte) ([]rgb, error) {
+	var lines []string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" {
+			lines = append(lines, line)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	if strings.EqualFold(ext, ".hex") {
+		colors := make([]rgb, 0, len(lines))
+		for i, line := range lines {
+			c, err := parseHex(line)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %w", i+1, err)
+			}
+			colors = append(colors, c)
+		}
+		return colors, nil
+	}
+
+	// JASC-PAL, a version line, the number of colors and one "r g b" line per color
+	if len(lines) < 3 || lines[0] != "JASC-PAL" {
+		return nil, fmt.Errorf("not a JASC palette")
+	}
+	count, err := strconv.Atoi(lines[2])
+	if err != nil || count != len(lines)-3 {
+		return nil, fmt.Errorf("expected %s colors, found %d", lines[2], len(lines)-3)
+	}
+	colors := make([]rgb, 0, count)
+	for i, line := range lines[3:] {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			return nil, fmt.Errorf("color %d: expected r g b", i+1)
+		}
+		var c rgb
+		for j := range c {
+			v, err := strconv.ParseUint(fields[j], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("color %d: %w", i+1, err)
+			}
+			c[j] = uint8(v)
+		}
+		colors = append(colors, c)
+	}
+	return colors, nil
+}
+
+func parseHex(s string) (rgb, error) {
+	s = strings.TrimPrefix(s, "#")
+	v, err := strconv.ParseUint(s, 16, 32)
+	if err != nil || len(s) != 6 {
+		return rgb{}, fmt.Errorf("invalid color %q", s)
+	}
+	return rgb{uint8(v >> 16), uint8(v >> 8), uint8(v)}, nil
+}
+
+// paletteLua returns a Lua module with a list of colors in the 0-1 range love.graphics.setColor expects
+func paletteLua(colors []rgb) []byte {
+	var b strings.Builder
+	b.WriteString("-- generated by nibs\nreturn {\n")
+	for _, c := range colors {
+		fmt.Fprintf(&b, "\t{%s, %s, %s, 1}, -- #%02x%02x%02x\n", channel(c[0]), channel(c[1]), channel(c[2]), c[0], c[1], c[2])
+	}
+	b.WriteString("}\n")
+	return []byte(b.String())
+}
+
+func channel(v uint8) string {
+	return strconv.FormatFloat(float64(v)/255, 'g', -1, 64)
+}
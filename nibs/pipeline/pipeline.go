@@ -0,0 +1,135 @@
+// Package pipeline implements the built-in asset steps that run while a project is bundled.
+// Steps never touch the project, they only change what ends up in the bundle.
+package pipeline
+
+import (
+	"fmt"
+	"path"
+	"sort"
+	"strings"
+)
+
+// Config selects the built-in steps, it is the [pipeline] section of nibs.toml
+type Config struct {
+	// Minify strips comments and indentation from Lua files, line numbers stay the same
+	Minify bool `toml:"minify,omitempty"`
+	// Palettes converts .pal and .hex palettes to Lua modules
+	Palettes bool `toml:"palettes,omitempty"`
+	// Atlas packs folders of PNGs into texture atlases
+	Atlas []Atlas `toml:"atlas,omitempty"`
+}
+
+// Atlas packs all PNGs below Dir into Output.png and writes the lookup table to Output.lua
+type Atlas struct {
+	// Dir is the slash separated folder with the sprites, relative to the project
+	Dir string `toml:"dir"`
+	// Output is the path of the atlas without extension, Dir if empty
+	Output string `toml:"output,omitempty"`
+	// Padding is the number of empty pixels around every sprite
+	Padding int `toml:"padding,omitempty"`
+}
+
+func (a Atlas) output() string {
+	if a.Output != "" {
+		return strings.TrimSuffix(a.Output, "/")
+	}
+	return strings.TrimSuffix(a.Dir, "/")
+}
+
+// contains reports whether the slash separated file is one of the sprites of the atlas
+func (a Atlas) contains(file string) bool {
+	return strings.EqualFold(path.Ext(file), ".png") && strings.HasPrefix(file, strings.TrimSuffix(a.Dir, "/")+"/")
+}
+
+// isPalette reports whether the file is converted by the palette step
+func isPalette(file string) bool {
+	switch strings.ToLower(path.Ext(file)) {
+	case ".pal", ".hex":
+		return true
+	}
+	return false
+}
+
+// paletteModule returns the name of the Lua file a palette is converted to
+func paletteModule(file string) string {
+	return strings.TrimSuffix(file, path.Ext(file)) + ".lua"
+}
+
+// Generated returns the sorted names of the files the steps add to the bundle
+func Generated(files []string, cfg Config) []string {
+	var generated []string
+	if cfg.Palettes {
+		for _, file := range files {
+			if isPalette(file) {
+				generated = append(generated, paletteModule(file))
+			}
+		}
+	}
+	for _, a := range cfg.Atlas {
+		generated = append(generated, a.output()+".png", a.output()+".lua")
+	}
+	sort.Strings(generated)
+	return generated
+}
+
+// Run applies the steps to the slash separated files of dir. It returns the files that are
+// bundled from the project as they are and the files the steps generated or changed.
+// Sources that were converted, like palettes and sprites, are left out of the bundle.
+func Run(dir string, files []string, cfg Config) ([]string, map[string][]byte, error) {
+	generated := map[string][]byte{}
+	exists := map[string]bool{}
+	for _, file := range files {
+		exists[file] = true
+	}
+	add := func(name string, data []byte) error {
+		if _, ok := generated[name]; ok || exists[name] {
+			return fmt.Errorf("%s would be overwritten by the pipeline", name)
+		}
+		generated[name] = data
+		return nil
+	}
+
+	var keep []string
+	converted := map[string]bool{}
+	for _, a := range cfg.Atlas {
+		image, lua, sprites, err := a.build(dir, files)
+		if err != nil {
+			return nil, nil, err
+		}
+		if err := add(a.output()+".png", image); err != nil {
+			return nil, nil, err
+		}
+		if err := add(a.output()+".lua", lua); err != nil {
+			return nil, nil, err
+		}
+		for _, sprite := range sprites {
+			converted[sprite] = true
+		}
+	}
+
+	for _, file := range files {
+		if converted[file] {
+			continue
+		}
+		switch {
+		case cfg.Palettes && isPalette(file):
+			lua, err := convertPalette(dir, file)
+			if err != nil {
+				return nil, nil, err
+			}
+			if err := add(paletteModule(file), lua); err != nil {
+				return nil, nil, err
+			}
+		case cfg.Minify && path.Ext(file) == ".lua":
+			src, err := readFile(dir, file)
+			if err != nil {
+				return nil, nil, err
+			}
+			generated[file] = Minify(src)
+			keep = append(keep, file)
+		default:
+			keep = append(keep, file)
+		}
+	}
+	return keep, generated, nil
+}
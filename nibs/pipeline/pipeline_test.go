@@ -0,0 +1,166 @@
+package pipeline
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestMinify(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{
+			name: "comments and indentation",
+			src:  "-- player\nlocal x = 1 -- speed\n\tif x   then\n\t\tprint(x)\n\tend\n",
+			want: "\nlocal x = 1\nif x then\nprint(x)\nend\n",
+		},
+		{
+			name: "block comments keep their lines",
+			src:  "--[[ a\nb ]] local a = 1\n--[==[\n]]\n]==]a=a--[[x]]+1",
+			want: "\nlocal a = 1\n\n\na=a +1",
+		},
+		{
+			name: "strings are left alone",
+			src:  "s = \"  -- no comment\"\nt = 'it\\'s -- '\nu = [[\n  keep  -- this\n]]\nv = [=[ ]] ]=]",
+			want: "s = \"  -- no comment\"\nt = 'it\\'s -- '\nu = [[\n  keep  -- this\n]]\nv = [=[ ]] ]=]",
+		},
+		{
+			name: "indexing",
+			src:  "t[ 1 ] = t [i]",
+			want: "t[ 1 ] = t [i]",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(Minify([]byte(tt.src))); got != tt.want {
+				t.Errorf("Minify() = %q, want %q", got, tt.want)
+			}
+			if strings.Count(tt.src, "\n") != strings.Count(tt.want, "\n") {
+				t.Errorf("line count changed")
+			}
+		})
+	}
+}
+
+func TestParsePalette(t *testing.T) {
+	jasc := "JASC-PAL\r\n0100\r\n2\r\n0 0 0\r\n255 128 0\r\n"
+	colors, err := parsePalette(".pal", []byte(jasc))
+	if err != nil {
+		t.Fatal(err)
+	}
+	hex, err := parsePalette(".hex", []byte("000000\nff8000\n"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := "-- generated by nibs\nreturn {\n\t{0, 0, 0, 1}, -- #000000\n\t{1, 0.5019607843137255, 0, 1}, -- #ff8000\n}\n"
+	if got := string(paletteLua(colors)); got != want {
+		t.Errorf("pal = %q, want %q", got, want)
+	}
+	if got := string(paletteLua(hex)); got != want {
+		t.Errorf("hex = %q, want %q", got, want)
+	}
+
+	for _, bad := range []string{"JASC-PAL\n0100\n3\n0 0 0\n", "GIMP Palette\n"} {
+		if _, err := parsePalette(".pal", []byte(bad)); err == nil {
+			t.Errorf("expected an error for %q", bad)
+		}
+	}
+	if _, err := parsePalette(".hex", []byte("fff\n")); err == nil {
+		t.Errorf("expected an error for a short color")
+	}
+}
+
+func writePNG(t *testing.T, path string, w, h int, c color.Color) {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(path, buf.Bytes(), 0o644); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestRun(t *testing.T) {
+	dir := t.TempDir()
+	red, blue := color.NRGBA{255, 0, 0, 255}, color.NRGBA{0, 0, 255, 255}
+	writePNG(t, filepath.Join(dir, "sprites", "hero.png"), 16, 16, red)
+	writePNG(t, filepath.Join(dir, "sprites", "enemies", "bat.png"), 8, 4, blue)
+	os.WriteFile(filepath.Join(dir, "main.lua"), []byte("-- game\nprint(1)\n"), 0o644)
+	os.WriteFile(filepath.Join(dir, "colors.hex"), []byte("ff0000\n"), 0o644)
+	files := []string{"colors.hex", "main.lua", "sprites/enemies/bat.png", "sprites/hero.png"}
+	cfg := Config{Minify: true, Palettes: true, Atlas: []Atlas{{Dir: "sprites", Output: "gfx/atlas", Padding: 1}}}
+
+	keep, generated, err := Run(dir, files, cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Join(keep, " ") != "main.lua" {
+		t.Errorf("kept %v", keep)
+	}
+	var names []string
+	for name := range generated {
+		names = append(names, name)
+	}
+	want := Generated(files, cfg)
+	if len(names) != len(want)+1 {
+		t.Errorf("generated %v, want %v and main.lua", names, want)
+	}
+	if string(generated["main.lua"]) != "\nprint(1)\n" {
+		t.Errorf("main.lua = %q", generated["main.lua"])
+	}
+	if !strings.Contains(string(generated["colors.lua"]), "{1, 0, 0, 1}") {
+		t.Errorf("colors.lua = %q", generated["colors.lua"])
+	}
+
+	wantLua := `-- generated by nibs
+return {
+	image = "gfx/atlas.png",
+	width = 32,
+	height = 18,
+	sprites = {
+		["enemies/bat"] = { x = 19, y = 1, w = 8, h = 4 },
+		["hero"] = { x = 1, y = 1, w = 16, h = 16 },
+	},
+}
+`
+	if got := string(generated["gfx/atlas.lua"]); got != wantLua {
+		t.Errorf("atlas.lua:\n%s\nwant:\n%s", got, wantLua)
+	}
+	img, err := png.Decode(bytes.NewReader(generated["gfx/atlas.png"]))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if c := color.NRGBAModel.Convert(img.At(1, 1)); c != red {
+		t.Errorf("hero pixel = %v", c)
+	}
+	if c := color.NRGBAModel.Convert(img.At(19, 1)); c != blue {
+		t.Errorf("bat pixel = %v", c)
+	}
+	if c := color.NRGBAModel.Convert(img.At(0, 0)); c != (color.NRGBA{}) {
+		t.Errorf("padding pixel = %v", c)
+	}
+
+	// generated files must not replace project files
+	os.WriteFile(filepath.Join(dir, "colors.lua"), []byte("return {}"), 0o644)
+	if _, _, err := Run(dir, append(files, "colors.lua"), cfg); err == nil {
+		t.Errorf("expected an error when colors.lua exists")
+	}
+}
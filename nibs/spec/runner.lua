@@ -0,0 +1,185 @@
+-- nibs test runner, generated into a temporary game by `nibs test`.
+-- nibs defines NIBS_TEST = { source = "...", files = { ... } } in front of this file.
+--
+-- Every test is reported on stdout as a single line with tab separated fields:
+--   @@nibs-test <status> <file> <name> <seconds> <message>
+-- status is "pass", "fail" or "skip", tabs and newlines in the message are escaped.
+local config = NIBS_TEST
+NIBS_TEST = nil
+
+package.path = config.source .. "/?.lua;" .. config.source .. "/?/init.lua;" .. package.path
+
+local stats = { failed = 0 }
+
+local function escape(s)
+	s = tostring(s or ""):gsub("\\", "\\\\"):gsub("\t", "\\t"):gsub("\n", "\\n")
+	return s
+end
+
+local function report(status, file, name, duration, message)
+	if status == "fail" then
+		stats.failed = stats.failed + 1
+	end
+	io.stdout:write(string.format("@@nibs-test\t%s\t%s\t%s\t%.6f\t%s\n", status, escape(file), escape(name), duration, escape(message)))
+end
+
+-- describe blocks form a tree, the root is the spec file itself
+local current
+
+local function block(name, parent)
+	return { name = name, parent = parent, items = {}, before = {}, after = {} }
+end
+
+function describe(name, fn)
+	local b = block(name, current)
+	table.insert(current.items, b)
+	local parent = current
+	current = b
+	fn()
+	current = parent
+end
+
+function it(name, fn)
+	table.insert(current.items, { name = name, fn = fn })
+end
+
+function pending(name)
+	table.insert(current.items, { name = name })
+end
+
+function before_each(fn)
+	table.insert(current.before, fn)
+end
+
+function after_each(fn)
+	table.insert(current.after, fn)
+end
+
+local function format(v)
+	if type(v) == "string" then
+		return string.format("%q", v)
+	end
+	return tostring(v)
+end
+
+local function same(a, b)
+	if type(a) ~= "table" or type(b) ~= "table" then
+		return a == b
+	end
+	for k, v in pairs(a) do
+		if not same(v, b[k]) then
+			return false
+		end
+	end
+	for k in pairs(b) do
+		if a[k] == nil then
+			return false
+		end
+	end
+	return true
+end
+
+local function fail(message, prefix)
+	error((prefix and prefix .. ": " or "") .. message, 3)
+end
+
+-- assert_equal compares with ==
+function assert_equal(actual, expected, message)
+	if actual ~= expected then
+		fail("expected " .. format(expected) .. ", got " .. format(actual), message)
+	end
+end
+
+-- assert_same compares tables by their content
+function assert_same(actual, expected, message)
+	if not same(actual, expected) then
+		fail("expected the same content as " .. format(expected) .. ", got " .. format(actual), message)
+	end
+end
+
+-- assert_error expects fn to raise an error, optionally matching the pattern
+function assert_error(fn, pattern, message)
+	local ok, err = pcall(fn)
+	if ok then
+		fail("expected an error", message)
+	end
+	if pattern and not tostring(err):find(pattern) then
+		fail("expected an error matching " .. format(pattern) .. ", got " .. format(err), message)
+	end
+end
+
+local function fullName(b, name)
+	local parts = { name }
+	while b.parent do
+		table.insert(parts, 1, b.name)
+		b = b.parent
+	end
+	return table.concat(parts, " ")
+end
+
+local function run(file, b, befores, afters)
+	local before, after = {}, {}
+	for _, f in ipairs(befores) do
+		table.insert(before, f)
+	end
+	for _, f in ipairs(b.before) do
+		table.insert(before, f)
+	end
+	for _, f in ipairs(b.after) do
+		table.insert(after, f)
+	end
+	for _, f in ipairs(afters) do
+		table.insert(after, f)
+	end
+
+	for _, item in ipairs(b.items) do
+		if item.items then
+			run(file, item, before, after)
+		elseif not item.fn then
+			report("skip", file, fullName(b, item.name), 0)
+		else
+			local start = os.clock()
+			local ok, err = pcall(function()
+				for _, f in ipairs(before) do
+					f()
+				end
+				item.fn()
+			end)
+			-- after_each runs even when the test failed
+			for _, f in ipairs(after) do
+				local afterOk, afterErr = pcall(f)
+				if ok and not afterOk then
+					ok, err = false, afterErr
+				end
+			end
+			report(ok and "pass" or "fail", file, fullName(b, item.name), os.clock() - start, not ok and err or nil)
+		end
+	end
+end
+
+for _, file in ipairs(config.files) do
+	current = block(file)
+	local chunk, err = loadfile(config.source .. "/" .. file)
+	if chunk then
+		local ok, loadErr = pcall(chunk)
+		err = not ok and loadErr or nil
+	end
+	if err then
+		report("fail", file, "(load)", 0, err)
+	else
+		run(file, current, {}, {})
+	end
+end
+
+io.stdout:flush()
+local code = stats.failed > 0 and 1 or 0
+if love then
+	-- LÖVE 11 quits with the value returned from the main loop
+	function love.run()
+		return function()
+			return code
+		end
+	end
+else
+	os.exit(code)
+end
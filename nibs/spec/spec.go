@@ -0,0 +1,299 @@
+// Package spec runs the Lua specs of a project with an injected runner, through LÖVE or a plain Lua binary.
+package spec
+
+import (
+	"bufio"
+	_ "embed"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Runner is the Lua source of the test runner
+//
+//go:embed runner.lua
+var Runner []byte
+
+// Conf is the conf.lua of the generated game, specs run without window, graphics and audio
+const Conf = `function love.conf(t)
+	t.modules.window = false
+	t.modules.graphics = false
+	t.modules.audio = false
+	t.modules.sound = false
+	t.modules.joystick = false
+	t.modules.video = false
+end
+`
+
+// prefix marks the lines of the runner in the output
+const prefix = "@@nibs-test\t"
+
+// Status of a single test
+type Status string
+
+const (
+	Pass Status = "pass"
+	Fail Status = "fail"
+	Skip Status = "skip"
+)
+
+// Result of a single test
+type Result struct {
+	File     string
+	Name     string
+	Status   Status
+	Duration time.Duration
+	Message  string
+}
+
+// IsSpec reports whether the file name is one of a spec, *_spec.lua or test_*.lua
+func IsSpec(name string) bool {
+	base := path.Base(filepath.ToSlash(name))
+	return strings.HasSuffix(base, "_spec.lua") || strings.HasPrefix(base, "test_") && strings.HasSuffix(base, ".lua")
+}
+
+// Discover returns the sorted, slash separated paths of all specs below dir.
+// Hidden folders and the slash separated folders in skip, like vendored batteries, are left out.
+func Discover(dir string, skip []string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(dir, func(p string, d os.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel != "." && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			for _, s := range skip {
+				if rel == path.Clean(s) {
+					return filepath.SkipDir
+				}
+			}
+			return nil
+		}
+		if IsSpec(rel) {
+			files = append(files, rel)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// Options select how the specs are run
+type Options struct {
+	// Dir is the source folder of the project, specs and required modules are relative to it
+	Dir string
+	// Files are the slash separated specs to run
+	Files []string
+	// Love is the love binary, used unless Lua is set
+	Love string
+	// Lua is a plain lua or luajit binary
+	Lua string
+	// Output receives everything the specs print
+	Output io.Writer
+}
+
+// Run runs the specs in a temporary game and returns the results
+func Run(opts Options) ([]Result, error) {
+	dir, err := filepath.Abs(opts.Dir)
+	if err != nil {
+		return nil, err
+	}
+	game, err := os.MkdirTemp("", "nibs-test-")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(game)
+	if err := os.WriteFile(filepath.Join(game, "main.lua"), Main(dir, opts.Files), 0o644); err != nil {
+		return nil, err
+	}
+
+	var c *exec.Cmd
+	if opts.Lua != "" {
+		c = exec.Command(opts.Lua, filepath.Join(game, "main.lua"))
+	} else {
+		if err := os.WriteFile(filepath.Join(game, "conf.lua"), []byte(Conf), 0o644); err != nil {
+			return nil, err
+		}
+		c = exec.Command(opts.Love, game)
+	}
+	c.Dir = dir
+	c.Stderr = opts.Output
+	stdout, err := c.StdoutPipe()
+	if err != nil {
+		return nil, err
+	}
+	if err := c.Start(); err != nil {
+		return nil, err
+	}
+	results, parseErr := Parse(stdout, opts.Output)
+	err = c.Wait()
+
+	// failing specs exit with 1, anything else means the runner did not finish
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) && hasFailures(results) {
+		err = nil
+	}
+	if err != nil {
+		return results, fmt.Errorf("test run failed: %w", err)
+	}
+	return results, parseErr
+}
+
+func hasFailures(results []Result) bool {
+	for _, r := range results {
+		if r.Status == Fail {
+			return true
+		}
+	}
+	return false
+}
+
+// Main returns the main.lua of the generated game, the runner with its configuration
+func Main(dir string, files []string) []byte {
+	var b strings.Builder
+	fmt.Fprintf(&b, "NIBS_TEST = { source = %s, files = {", luaString(filepath.ToSlash(dir)))
+	for i, f := range files {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(luaString(f))
+	}
+	// keep the line numbers of the runner by not adding a line
+	b.WriteString("} } ")
+	b.Write(Runner)
+	return []byte(b.String())
+}
+
+// luaString quotes s as a Lua string literal
+func luaString(s string) string {
+	return `"` + strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s) + `"`
+}
+
+// Parse reads the output of the runner, lines that are no results are copied to w
+func Parse(r io.Reader, w io.Writer) ([]Result, error) {
+	var results []Result
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		line := scanner.Text()
+		if !strings.HasPrefix(line, prefix) {
+			if w != nil {
+				fmt.Fprintln(w, line)
+			}
+			continue
+		}
+		fields := strings.SplitN(strings.TrimPrefix(line, prefix), "\t", 5)
+		if len(fields) != 5 {
+			return results, fmt.Errorf("malformed result: %q", line)
+		}
+		seconds, _ := strconv.ParseFloat(fields[3], 64)
+		results = append(results, Result{
+			Status:   Status(fields[0]),
+			File:     unescape(fields[1]),
+			Name:     unescape(fields[2]),
+			Duration: time.Duration(seconds * float64(time.Second)),
+			Message:  unescape(fields[4]),
+		})
+	}
+	return results, scanner.Err()
+}
+
+func unescape(s string) string {
+	return strings.NewReplacer(`\\`, `\`, `\t`, "\t", `\n`, "\n").Replace(s)
+}
+
+// Summary counts the results by status
+func Summary(results []Result) map[Status]int {
+	counts := map[Status]int{}
+	for _, r := range results {
+		counts[r.Status]++
+	}
+	return counts
+}
+
+type junitSuites struct {
+	XMLName  xml.Name     `xml:"testsuites"`
+	Tests    int          `xml:"tests,attr"`
+	Failures int          `xml:"failures,attr"`
+	Skipped  int          `xml:"skipped,attr"`
+	Time     float64      `xml:"time,attr"`
+	Suites   []junitSuite `xml:"testsuite"`
+}
+
+type junitSuite struct {
+	Name     string      `xml:"name,attr"`
+	Tests    int         `xml:"tests,attr"`
+	Failures int         `xml:"failures,attr"`
+	Skipped  int         `xml:"skipped,attr"`
+	Time     float64     `xml:"time,attr"`
+	Cases    []junitCase `xml:"testcase"`
+}
+
+type junitCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      float64       `xml:"time,attr"`
+	Failure   *junitFailure `xml:"failure,omitempty"`
+	Skipped   *struct{}     `xml:"skipped,omitempty"`
+}
+
+type junitFailure struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",chardata"`
+}
+
+// JUnit writes the results as JUnit XML with one test suite per spec file
+func JUnit(w io.Writer, results []Result) error {
+	suites := junitSuites{}
+	index := map[string]int{}
+	for _, r := range results {
+		i, ok := index[r.File]
+		if !ok {
+			i = len(suites.Suites)
+			index[r.File] = i
+			suites.Suites = append(suites.Suites, junitSuite{Name: r.File})
+		}
+		suite := &suites.Suites[i]
+		c := junitCase{Name: r.Name, Classname: r.File, Time: r.Duration.Seconds()}
+		switch r.Status {
+		case Fail:
+			message, _, _ := strings.Cut(r.Message, "\n")
+			c.Failure = &junitFailure{Message: message, Text: r.Message}
+			suite.Failures++
+			suites.Failures++
+		case Skip:
+			c.Skipped = &struct{}{}
+			suite.Skipped++
+			suites.Skipped++
+		}
+		suite.Tests++
+		suite.Time += c.Time
+		suite.Cases = append(suite.Cases, c)
+		suites.Tests++
+		suites.Time += c.Time
+	}
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(suites); err != nil {
+		return err
+	}
+	_, err := io.WriteString(w, "\n")
+	return err
+}
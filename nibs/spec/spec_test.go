@@ -0,0 +1,177 @@
+package spec
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	lua "github.com/yuin/gopher-lua"
+)
+
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	t.Helper()
+	for name, content := range files {
+		p := filepath.Join(dir, filepath.FromSlash(name))
+		if err := os.MkdirAll(filepath.Dir(p), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(p, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+// runEmbedded runs the generated main.lua with gopher-lua instead of a Lua binary and returns its output and exit code
+func runEmbedded(t *testing.T, main []byte) (string, int) {
+	t.Helper()
+	var out strings.Builder
+	write := func(L *lua.LState, from int) {
+		for i := from; i <= L.GetTop(); i++ {
+			out.WriteString(L.ToStringMeta(L.Get(i)).String())
+		}
+	}
+
+	L := lua.NewState()
+	defer L.Close()
+	stdout := L.NewTable()
+	L.SetField(stdout, "write", L.NewFunction(func(L *lua.LState) int {
+		write(L, 2)
+		return 0
+	}))
+	L.SetField(stdout, "flush", L.NewFunction(func(L *lua.LState) int { return 0 }))
+	L.SetField(L.GetGlobal("io"), "stdout", stdout)
+	L.SetGlobal("print", L.NewFunction(func(L *lua.LState) int {
+		write(L, 1)
+		out.WriteString("\n")
+		return 0
+	}))
+	code := -1
+	L.SetField(L.GetGlobal("os"), "exit", L.NewFunction(func(L *lua.LState) int {
+		code = L.CheckInt(1)
+		return 0
+	}))
+	if err := L.DoString(string(main)); err != nil {
+		t.Fatal(err)
+	}
+	return out.String(), code
+}
+
+func TestRunner(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"player.lua": "local player = {}\nfunction player.new() return { hp = 10 } end\nreturn player",
+		"spec/player_spec.lua": `local player = require("player")
+describe("player", function()
+	local p
+	before_each(function() p = player.new() end)
+	it("starts with full health", function()
+		assert_equal(p.hp, 10)
+	end)
+	describe("damage", function()
+		it("loses health", function()
+			p.hp = p.hp - 3
+			assert_equal(p.hp, 8, "hp")
+		end)
+		pending("dies")
+	end)
+end)
+it("compares tables", function()
+	assert_same({ 1, { x = 2 } }, { 1, { x = 2 } })
+	assert_error(function() error("boom") end, "boom")
+end)
+print("hello from the spec")`,
+		"test_broken.lua": "it('x', function(",
+	})
+	files := []string{"spec/player_spec.lua", "test_broken.lua"}
+
+	out, code := runEmbedded(t, Main(dir, files))
+	if code != 1 {
+		t.Errorf("exit code %d, want 1", code)
+	}
+	var printed bytes.Buffer
+	results, err := Parse(strings.NewReader(out), &printed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if printed.String() != "hello from the spec\n" {
+		t.Errorf("printed %q", printed.String())
+	}
+
+	want := []Result{
+		{File: "spec/player_spec.lua", Name: "player starts with full health", Status: Pass},
+		{File: "spec/player_spec.lua", Name: "player damage loses health", Status: Fail, Message: "hp: expected 8, got 7"},
+		{File: "spec/player_spec.lua", Name: "player damage dies", Status: Skip},
+		{File: "spec/player_spec.lua", Name: "compares tables", Status: Pass},
+		{File: "test_broken.lua", Name: "(load)", Status: Fail},
+	}
+	if len(results) != len(want) {
+		t.Fatalf("got %d results, want %d: %+v", len(results), len(want), results)
+	}
+	for i, r := range results {
+		w := want[i]
+		if r.File != w.File || r.Name != w.Name || r.Status != w.Status || !strings.Contains(r.Message, w.Message) {
+			t.Errorf("result %d = %+v, want %+v", i, r, w)
+		}
+	}
+	if results[4].Message == "" {
+		t.Errorf("the syntax error is missing")
+	}
+
+	counts := Summary(results)
+	if counts[Pass] != 2 || counts[Fail] != 2 || counts[Skip] != 1 {
+		t.Errorf("summary %v", counts)
+	}
+}
+
+func TestParseEscapes(t *testing.T) {
+	results, err := Parse(strings.NewReader("@@nibs-test\tfail\ta.lua\ttab\\tname\t0.5\tline 1\\nline 2 \\\\n\n"), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(results) != 1 || results[0].Name != "tab\tname" || results[0].Message != "line 1\nline 2 \\n" || results[0].Duration.Seconds() != 0.5 {
+		t.Errorf("results = %+v", results)
+	}
+}
+
+func TestDiscover(t *testing.T) {
+	dir := t.TempDir()
+	writeFiles(t, dir, map[string]string{
+		"main.lua":                 "",
+		"player_spec.lua":          "",
+		"spec/test_enemy.lua":      "",
+		"spec/helper.lua":          "",
+		".git/test_hook.lua":       "",
+		"lib/hump/spec/x_spec.lua": "",
+	})
+	files, err := Discover(dir, []string{"lib/hump"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := strings.Join(files, " "); got != "player_spec.lua spec/test_enemy.lua" {
+		t.Errorf("Discover() = %s", got)
+	}
+}
+
+func TestJUnit(t *testing.T) {
+	var out bytes.Buffer
+	err := JUnit(&out, []Result{
+		{File: "a_spec.lua", Name: "works", Status: Pass},
+		{File: "a_spec.lua", Name: "breaks", Status: Fail, Message: "a_spec.lua:3: expected 1, got 2\nstack"},
+		{File: "b_spec.lua", Name: "later", Status: Skip},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, want := range []string{
+		`<testsuites tests="3" failures="1" skipped="1"`,
+		`<testsuite name="a_spec.lua" tests="2" failures="1" skipped="0"`,
+		`<failure message="a_spec.lua:3: expected 1, got 2">a_spec.lua:3: expected 1, got 2&#xA;stack</failure>`,
+		`<skipped></skipped>`,
+	} {
+		if !strings.Contains(out.String(), want) {
+			t.Errorf("missing %s in\n%s", want, out.String())
+		}
+	}
+}
@@ -1,16 +1,93 @@
 package main
 
 import (
+	"flag"
 	"fmt"
+	"io"
 	"net/url"
 	"os"
 	"strings"
 )
 
+// modes are the URL components with their own escaping rules
+var modes = []string{"form", "query", "path", "fragment", "userinfo"}
+
 func urlEncode(input string) string {
 	return url.QueryEscape(input)
 }
 
+// encode escapes input for a component of a URL
+func encode(input, mode string) (string, error) {
+	switch mode {
+	case "form":
+		// application/x-www-form-urlencoded, spaces become +
+		return urlEncode(input), nil
+	case "query":
+		// a query key or value, spaces become %20 and + stays unambiguous
+		return strings.ReplaceAll(urlEncode(input), "+", "%20"), nil
+	case "path":
+		// every segment is escaped on its own, slashes separate them
+		segments := strings.Split(input, "/")
+		for i, segment := range segments {
+			segments[i] = url.PathEscape(segment)
+		}
+		return strings.Join(segments, "/"), nil
+	case "fragment":
+		return (&url.URL{Fragment: input}).EscapedFragment(), nil
+	case "userinfo":
+		return url.User(input).String(), nil
+	}
+	return "", fmt.Errorf("unknown mode %q, expected %s", mode, strings.Join(modes, ", "))
+}
+
+// decode reverses the percent escapes of input, in form mode + is a space as well.
+// Invalid escapes are reported with their position, counted in bytes from 1.
+func decode(input, mode string) (string, error) {
+	if !validMode(mode) {
+		return "", fmt.Errorf("unknown mode %q, expected %s", mode, strings.Join(modes, ", "))
+	}
+	var b strings.Builder
+	for i := 0; i < len(input); i++ {
+		switch c := input[i]; {
+		case c == '%':
+			if i+2 >= len(input) || !isHex(input[i+1]) || !isHex(input[i+2]) {
+				escape := input[i:min(i+3, len(input))]
+				return "", fmt.Errorf("invalid percent escape %q at position %d", escape, i+1)
+			}
+			b.WriteByte(unhex(input[i+1])<<4 | unhex(input[i+2]))
+			i += 2
+		case c == '+' && mode == "form":
+			b.WriteByte(' ')
+		default:
+			b.WriteByte(c)
+		}
+	}
+	return b.String(), nil
+}
+
+func validMode(mode string) bool {
+	for _, m := range modes {
+		if m == mode {
+			return true
+		}
+	}
+	return false
+}
+
+func isHex(c byte) bool {
+	return '0' <= c && c <= '9' || 'a' <= c && c <= 'f' || 'A' <= c && c <= 'F'
+}
+
+func unhex(c byte) byte {
+	switch {
+	case '0' <= c && c <= '9':
+		return c - '0'
+	case 'a' <= c && c <= 'f':
+		return c - 'a' + 10
+	}
+	return c - 'A' + 10
+}
+
 func main() {
 	if err := run(os.Args); err != nil {
 		fmt.Fprintln(os.Stderr, err)
@@ -18,13 +95,44 @@ func main() {
 	}
 }
 
+// usage describes all forms of the command
+func usage() string {
+	return fmt.Sprintf(`Usage: urlencode [-d] [--mode %s] [--] <string>...
+       urlencode parse [--json] <url>
+       urlencode build <base url> [key=value]...
+Use -- before strings that start with - or are the words parse or build.`, strings.Join(modes, "|"))
+}
+
 func run(args []string) error {
-	if len(args) < 2 {
-		return fmt.Errorf("Usage: urlencode <string to encode>...")
+	if len(args) > 1 {
+		switch args[1] {
+		case "parse":
+			return runParse(args[2:], os.Stdout)
+		case "build":
+			return runBuild(args[2:], os.Stdout)
+		}
 	}
 
-	input := strings.Join(args[1:], " ")
-	encoded := urlEncode(input)
-	fmt.Println(encoded)
+	fs := flag.NewFlagSet("urlencode", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	decodeInput := fs.Bool("d", false, "decode instead of encode")
+	mode := fs.String("mode", "form", "escaping rules of the URL component: "+strings.Join(modes, ", "))
+	if err := fs.Parse(args[1:]); err != nil {
+		return fmt.Errorf("%w\n%s", err, usage())
+	}
+	if fs.NArg() < 1 {
+		return fmt.Errorf("%s", usage())
+	}
+
+	input := strings.Join(fs.Args(), " ")
+	convert := encode
+	if *decodeInput {
+		convert = decode
+	}
+	output, err := convert(input, *mode)
+	if err != nil {
+		return err
+	}
+	fmt.Println(output)
 	return nil
 }
@@ -0,0 +1,157 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"strings"
+)
+
+// param is a single query parameter, a URL may repeat keys
+type param struct {
+	Key   string `json:"key"`
+	Value string `json:"value"`
+}
+
+// parts are the decoded components of a URL
+type parts struct {
+	Scheme   string  `json:"scheme"`
+	User     string  `json:"user,omitempty"`
+	Password string  `json:"password,omitempty"`
+	Host     string  `json:"host"`
+	Port     string  `json:"port,omitempty"`
+	Path     string  `json:"path"`
+	Opaque   string  `json:"opaque,omitempty"`
+	Query    []param `json:"query"`
+	Fragment string  `json:"fragment,omitempty"`
+}
+
+// parseURL splits rawURL into its decoded components, query parameters keep their order
+func parseURL(rawURL string) (parts, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return parts{}, err
+	}
+	query, err := parseQuery(u.RawQuery)
+	if err != nil {
+		return parts{}, err
+	}
+	p := parts{
+		Scheme:   u.Scheme,
+		Host:     u.Hostname(),
+		Port:     u.Port(),
+		Path:     u.Path,
+		Opaque:   u.Opaque,
+		Query:    query,
+		Fragment: u.Fragment,
+	}
+	if u.User != nil {
+		p.User = u.User.Username()
+		p.Password, _ = u.User.Password()
+	}
+	return p, nil
+}
+
+// parseQuery decodes a query string like form data, unlike url.ParseQuery it keeps the order and ; as part of a value
+func parseQuery(rawQuery string) ([]param, error) {
+	query := []param{}
+	if rawQuery == "" {
+		return query, nil
+	}
+	for _, pair := range strings.Split(rawQuery, "&") {
+		if pair == "" {
+			continue
+		}
+		rawKey, rawValue, _ := strings.Cut(pair, "=")
+		key, err := decode(rawKey, "form")
+		if err != nil {
+			return nil, fmt.Errorf("query key %q: %w", rawKey, err)
+		}
+		value, err := decode(rawValue, "form")
+		if err != nil {
+			return nil, fmt.Errorf("query value of %q: %w", key, err)
+		}
+		query = append(query, param{Key: key, Value: value})
+	}
+	return query, nil
+}
+
+// buildURL adds the key=value args to the query of base, after the parameters it already has
+func buildURL(base string, args []string) (string, error) {
+	u, err := url.Parse(base)
+	if err != nil {
+		return "", err
+	}
+	pairs := []string{}
+	if u.RawQuery != "" {
+		pairs = append(pairs, u.RawQuery)
+	}
+	for _, arg := range args {
+		key, value, ok := strings.Cut(arg, "=")
+		if key == "" {
+			return "", fmt.Errorf("invalid parameter %q, expected key=value", arg)
+		}
+		pair := urlEncode(key)
+		if ok {
+			pair += "=" + urlEncode(value)
+		}
+		pairs = append(pairs, pair)
+	}
+	u.RawQuery = strings.Join(pairs, "&")
+	return u.String(), nil
+}
+
+// runParse implements "urlencode parse [--json] <url>"
+func runParse(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("parse", flag.ContinueOnError)
+	fs.SetOutput(io.Discard)
+	jsonOutput := fs.Bool("json", false, "print the parts as JSON")
+	if err := fs.Parse(args); err != nil || fs.NArg() != 1 {
+		return fmt.Errorf("Usage: urlencode parse [--json] <url>")
+	}
+
+	p, err := parseURL(fs.Arg(0))
+	if err != nil {
+		return err
+	}
+	if *jsonOutput {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(p)
+	}
+
+	lines := []param{
+		{"scheme", p.Scheme},
+		{"user", p.User},
+		{"password", p.Password},
+		{"host", p.Host},
+		{"port", p.Port},
+		{"path", p.Path},
+		{"opaque", p.Opaque},
+	}
+	for _, q := range p.Query {
+		lines = append(lines, param{"query", q.Key + "=" + q.Value})
+	}
+	lines = append(lines, param{"fragment", p.Fragment})
+	for _, line := range lines {
+		if line.Value != "" {
+			fmt.Fprintf(w, "%-10s%s\n", line.Key, line.Value)
+		}
+	}
+	return nil
+}
+
+// runBuild implements "urlencode build <base> [key=value]..."
+func runBuild(args []string, w io.Writer) error {
+	if len(args) < 1 {
+		return fmt.Errorf("Usage: urlencode build <base url> [key=value]...")
+	}
+	u, err := buildURL(args[0], args[1:])
+	if err != nil {
+		return err
+	}
+	fmt.Fprintln(w, u)
+	return nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// runConvert implements "uuid convert [--from format] --to format <id>..."
+func runConvert(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("convert", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: uuid convert [--from format] --to format <id>...")
+		fs.PrintDefaults()
+	}
+	from := fs.String("from", "auto", "format of the input: auto, "+strings.Join(formats, ", ")+" or ulid")
+	to := fs.String("to", "standard", "format of the output: "+strings.Join(formats, ", ")+" or ulid")
+	fs.Parse(args)
+
+	// flags may follow the IDs
+	var ids []string
+	for fs.NArg() > 0 {
+		ids = append(ids, fs.Arg(0))
+		fs.Parse(fs.Args()[1:])
+	}
+	if len(ids) == 0 {
+		fs.Usage()
+		return fmt.Errorf("missing ID")
+	}
+
+	for _, s := range ids {
+		id, err := decode(s, *from)
+		if err != nil {
+			return fmt.Errorf("invalid ID %q: %w", s, err)
+		}
+		out, err := encode(id, *to)
+		if err != nil {
+			return err
+		}
+		fmt.Fprintln(w, out)
+	}
+	return nil
+}
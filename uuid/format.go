@@ -0,0 +1,155 @@
+package main
+
+import (
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// formats are the encodings of the 16 bytes of an ID
+var formats = []string{"standard", "upper", "hex", "urn", "base32", "base58", "base64url"}
+
+// crockford is the base32 alphabet of Douglas Crockford, also used by ULID
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// base58Alphabet is the Bitcoin alphabet without 0, O, I and l
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+// encode formats id in one of the formats, ulid is another name for base32
+func encode(id uuid.UUID, format string) (string, error) {
+	switch format {
+	case "standard":
+		return id.String(), nil
+	case "upper":
+		return strings.ToUpper(id.String()), nil
+	case "hex":
+		return hex.EncodeToString(id[:]), nil
+	case "urn":
+		return id.URN(), nil
+	case "base32", "ulid":
+		return encodeBase32(id), nil
+	case "base58":
+		return encodeBase58(id), nil
+	case "base64url":
+		return base64.RawURLEncoding.EncodeToString(id[:]), nil
+	}
+	return "", fmt.Errorf("unknown format %q, expected %s or ulid", format, strings.Join(formats, ", "))
+}
+
+// decode parses s in one of the formats. The format auto accepts the standard forms
+// (with or without hyphens, braced or URN) and 26 character base32, base58 and base64url
+// cannot be told apart and have to be given.
+func decode(s, format string) (uuid.UUID, error) {
+	s = strings.TrimSpace(s)
+	switch format {
+	case "auto":
+		if id, err := uuid.Parse(s); err == nil {
+			return id, nil
+		}
+		if len(s) == 26 {
+			return decodeBase32(s)
+		}
+		return uuid.Nil, fmt.Errorf("cannot detect the format of %q, base58 and base64url need --from", s)
+	case "standard", "upper", "hex", "urn":
+		return uuid.Parse(s)
+	case "base32", "ulid":
+		return decodeBase32(s)
+	case "base58":
+		return decodeBase58(s)
+	case "base64url":
+		b, err := base64.RawURLEncoding.DecodeString(s)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid base64url: %w", err)
+		}
+		return uuid.FromBytes(b)
+	}
+	return uuid.Nil, fmt.Errorf("unknown format %q, expected auto, %s or ulid", format, strings.Join(formats, ", "))
+}
+
+// encodeBase32 encodes the 128 bits as 26 Crockford base32 characters, the first one holds the top 3 bits
+func encodeBase32(id uuid.UUID) string {
+	hi, lo := binary.BigEndian.Uint64(id[:8]), binary.BigEndian.Uint64(id[8:])
+	var out [26]byte
+	for i := 25; i >= 0; i-- {
+		out[i] = crockford[lo&0x1f]
+		lo = lo>>5 | hi<<59
+		hi >>= 5
+	}
+	return string(out[:])
+}
+
+func decodeBase32(s string) (uuid.UUID, error) {
+	if len(s) != 26 {
+		return uuid.Nil, fmt.Errorf("invalid base32 length %d, expected 26", len(s))
+	}
+	var hi, lo uint64
+	for i, c := range strings.ToUpper(s) {
+		// Crockford decodes the look-alikes
+		switch c {
+		case 'O':
+			c = '0'
+		case 'I', 'L':
+			c = '1'
+		}
+		v := strings.IndexRune(crockford, c)
+		if v < 0 {
+			return uuid.Nil, fmt.Errorf("invalid base32 character %q at position %d", c, i+1)
+		}
+		if i == 0 && v > 7 {
+			return uuid.Nil, fmt.Errorf("base32 value overflows 128 bits")
+		}
+		hi = hi<<5 | lo>>59
+		lo = lo<<5 | uint64(v)
+	}
+	var id uuid.UUID
+	binary.BigEndian.PutUint64(id[:8], hi)
+	binary.BigEndian.PutUint64(id[8:], lo)
+	return id, nil
+}
+
+// encodeBase58 encodes id as a number in the Bitcoin alphabet, leading zero bytes become 1
+func encodeBase58(id uuid.UUID) string {
+	n := new(big.Int).SetBytes(id[:])
+	base, mod := big.NewInt(58), new(big.Int)
+	var out []byte
+	for n.Sign() > 0 {
+		n.DivMod(n, base, mod)
+		out = append(out, base58Alphabet[mod.Int64()])
+	}
+	for _, b := range id {
+		if b != 0 {
+			break
+		}
+		out = append(out, base58Alphabet[0])
+	}
+	for i, j := 0, len(out)-1; i < j; i, j = i+1, j-1 {
+		out[i], out[j] = out[j], out[i]
+	}
+	return string(out)
+}
+
+func decodeBase58(s string) (uuid.UUID, error) {
+	if s == "" {
+		return uuid.Nil, fmt.Errorf("empty base58")
+	}
+	n := new(big.Int)
+	base := big.NewInt(58)
+	for i, c := range s {
+		v := strings.IndexRune(base58Alphabet, c)
+		if v < 0 {
+			return uuid.Nil, fmt.Errorf("invalid base58 character %q at position %d", c, i+1)
+		}
+		n.Mul(n, base).Add(n, big.NewInt(int64(v)))
+	}
+	if n.BitLen() > 128 {
+		return uuid.Nil, fmt.Errorf("base58 value overflows 128 bits")
+	}
+	var id uuid.UUID
+	n.FillBytes(id[:])
+	return id, nil
+}
@@ -0,0 +1,200 @@
+package main
+
+import (
+	"bufio"
+	"crypto/rand"
+	"encoding/binary"
+	"fmt"
+	"io"
+	mrand "math/rand/v2"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// seededEpoch is the clock of time-based versions with --seed and without --time
+var seededEpoch = time.Date(2000, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// generator creates the IDs of a run from one source of randomness and one clock.
+// The time-based versions 6, 7 and ulid are strictly increasing within a run, even within the same clock tick.
+type generator struct {
+	version string
+	// namespace and name of version 3 and 5
+	namespace uuid.UUID
+	name      string
+
+	rand io.Reader
+	now  func() time.Time
+
+	// lastV7 is the millisecond timestamp and the 12 bit counter of the last version 7 UUID
+	lastV7 uint64
+	// lastV6 are the 100ns intervals since 1582 of the last version 6 UUID
+	lastV6   uint64
+	clockSeq uint16
+	node     []byte
+	lastULID uuid.UUID
+}
+
+// newGenerator checks the version and its arguments, a seed makes the randomness deterministic
+// and a non-zero start freezes the clock.
+func newGenerator(version, namespace, name string, seed *uint64, start time.Time) (*generator, error) {
+	g := &generator{version: version, name: name}
+	switch version {
+	case "3", "5":
+		if namespace == "" || name == "" {
+			return nil, fmt.Errorf("version %s needs --namespace and --name", version)
+		}
+		ns, err := parseNamespace(namespace)
+		if err != nil {
+			return nil, err
+		}
+		g.namespace = ns
+	case "4", "6", "7", "ulid", "random":
+		if namespace != "" || name != "" {
+			return nil, fmt.Errorf("--namespace and --name are only used by version 3 and 5")
+		}
+	default:
+		return nil, fmt.Errorf("unsupported version %q, expected 3, 4, 5, 6, 7, ulid or random", version)
+	}
+
+	g.rand = bufio.NewReaderSize(rand.Reader, 4096)
+	if seed != nil {
+		var chachaSeed [32]byte
+		binary.LittleEndian.PutUint64(chachaSeed[:], *seed)
+		g.rand = seededReader{mrand.New(mrand.NewChaCha8(chachaSeed))}
+		if start.IsZero() {
+			start = seededEpoch
+		}
+	}
+	g.now = time.Now
+	if !start.IsZero() {
+		g.now = func() time.Time { return start }
+	}
+	return g, nil
+}
+
+// seededReader reads deterministic bytes from a seeded random number generator
+type seededReader struct {
+	r *mrand.Rand
+}
+
+func (s seededReader) Read(p []byte) (int, error) {
+	var buf [8]byte
+	for i := 0; i < len(p); i += 8 {
+		binary.LittleEndian.PutUint64(buf[:], s.r.Uint64())
+		copy(p[i:], buf[:])
+	}
+	return len(p), nil
+}
+
+// next returns the next ID
+func (g *generator) next() (uuid.UUID, error) {
+	switch g.version {
+	case "3":
+		return uuid.NewMD5(g.namespace, []byte(g.name)), nil
+	case "5":
+		return uuid.NewSHA1(g.namespace, []byte(g.name)), nil
+	case "4":
+		return g.v4()
+	case "6":
+		return g.v6()
+	case "7":
+		return g.v7()
+	case "ulid":
+		return g.ulid()
+	}
+	return g.random()
+}
+
+func (g *generator) random() (uuid.UUID, error) {
+	var id uuid.UUID
+	_, err := io.ReadFull(g.rand, id[:])
+	return id, err
+}
+
+// setVersion sets the version and the variant of RFC 9562
+func setVersion(id *uuid.UUID, version byte) {
+	id[6] = id[6]&0x0f | version<<4
+	id[8] = id[8]&0x3f | 0x80
+}
+
+func (g *generator) v4() (uuid.UUID, error) {
+	id, err := g.random()
+	setVersion(&id, 4)
+	return id, err
+}
+
+// v7 uses the 12 bits of rand_a as counter, it starts randomly in the lower half for every new millisecond
+// and the timestamp moves on when it overflows (method 1 of RFC 9562, section 6.2)
+func (g *generator) v7() (uuid.UUID, error) {
+	id, err := g.random()
+	if err != nil {
+		return id, err
+	}
+	state := uint64(g.now().UnixMilli())<<12 | uint64(binary.BigEndian.Uint16(id[6:8])&0x07ff)
+	if state>>12 <= g.lastV7>>12 {
+		state = g.lastV7 + 1
+	}
+	g.lastV7 = state
+
+	ms := state >> 12
+	binary.BigEndian.PutUint16(id[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	binary.BigEndian.PutUint16(id[6:8], uint16(state&0x0fff))
+	setVersion(&id, 7)
+	return id, nil
+}
+
+// gregorianOffset is the number of 100ns intervals between 1582-10-15 and the Unix epoch
+const gregorianOffset = 0x01b21dd213814000
+
+// v6 stores the timestamp most significant bits first, clock sequence and node are random for the run
+func (g *generator) v6() (uuid.UUID, error) {
+	if g.node == nil {
+		var b [8]byte
+		if _, err := io.ReadFull(g.rand, b[:]); err != nil {
+			return uuid.Nil, err
+		}
+		g.clockSeq = binary.BigEndian.Uint16(b[0:2]) & 0x3fff
+		// the multicast bit marks a random node
+		g.node = append([]byte{b[2] | 0x01}, b[3:8]...)
+	}
+	ts := uint64(g.now().UnixNano()/100 + gregorianOffset)
+	ts = max(ts, g.lastV6+1)
+	g.lastV6 = ts
+
+	var id uuid.UUID
+	binary.BigEndian.PutUint32(id[0:4], uint32(ts>>28))
+	binary.BigEndian.PutUint16(id[4:6], uint16(ts>>12))
+	binary.BigEndian.PutUint16(id[6:8], uint16(ts&0x0fff))
+	binary.BigEndian.PutUint16(id[8:10], g.clockSeq)
+	copy(id[10:], g.node)
+	setVersion(&id, 6)
+	return id, nil
+}
+
+// ulid has 48 bits of milliseconds and 80 random bits, within the same millisecond the previous ULID is incremented
+func (g *generator) ulid() (uuid.UUID, error) {
+	ms := uint64(g.now().UnixMilli())
+	last := uint64(binary.BigEndian.Uint16(g.lastULID[0:2]))<<32 | uint64(binary.BigEndian.Uint32(g.lastULID[2:6]))
+	if g.lastULID != uuid.Nil && ms <= last {
+		id := g.lastULID
+		for i := len(id) - 1; i >= 0; i-- {
+			id[i]++
+			if id[i] != 0 {
+				break
+			}
+		}
+		g.lastULID = id
+		return id, nil
+	}
+
+	var id uuid.UUID
+	if _, err := io.ReadFull(g.rand, id[6:]); err != nil {
+		return id, err
+	}
+	binary.BigEndian.PutUint16(id[0:2], uint16(ms>>32))
+	binary.BigEndian.PutUint32(id[2:6], uint32(ms))
+	g.lastULID = id
+	return id, nil
+}
@@ -4,4 +4,4 @@ go 1.22.0
 
 toolchain go1.23.3
 
-require github.com/google/uuid v1.3.0
+require github.com/google/uuid v1.6.0
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// versionNames describe the versions of RFC 9562
+var versionNames = map[uuid.Version]string{
+	1: "time-based",
+	2: "DCE security",
+	3: "name-based, MD5",
+	4: "random",
+	5: "name-based, SHA-1",
+	6: "reordered time-based",
+	7: "Unix time-based",
+	8: "custom",
+}
+
+// info is what a UUID contains, the optional fields only exist for some versions
+type info struct {
+	UUID          string `json:"uuid"`
+	Version       int    `json:"version"`
+	VersionName   string `json:"version_name"`
+	Variant       string `json:"variant"`
+	Time          string `json:"time,omitempty"`
+	ClockSequence *int   `json:"clock_sequence,omitempty"`
+	Node          string `json:"node,omitempty"`
+}
+
+// inspect decodes the contents of id
+func inspect(id uuid.UUID) info {
+	i := info{
+		UUID:        id.String(),
+		Version:     int(id.Version()),
+		VersionName: versionNames[id.Version()],
+		Variant:     id.Variant().String(),
+	}
+	switch id {
+	case uuid.Nil:
+		i.VersionName = "nil"
+		return i
+	case uuid.Max:
+		i.VersionName = "max"
+		return i
+	}
+	if i.VersionName == "" {
+		i.VersionName = "unknown"
+	}
+	if id.Variant() != uuid.RFC4122 {
+		return i
+	}
+
+	if t, ok := timestamp(id); ok {
+		i.Time = t.UTC().Format(time.RFC3339Nano)
+	}
+	switch id.Version() {
+	case 1, 6:
+		seq := id.ClockSequence()
+		i.ClockSequence = &seq
+		i.Node = hex.EncodeToString(id.NodeID())
+	}
+	return i
+}
+
+// timestamp returns the time embedded in UUIDs of version 1, 6 and 7
+func timestamp(id uuid.UUID) (time.Time, bool) {
+	switch id.Version() {
+	case 1:
+		sec, nsec := id.Time().UnixTime()
+		return time.Unix(sec, nsec), true
+	case 6:
+		// time_high (32 bits), time_mid (16 bits), version (4 bits) and time_low (12 bits)
+		ts := uint64(binary.BigEndian.Uint32(id[0:4]))<<28 |
+			uint64(binary.BigEndian.Uint16(id[4:6]))<<12 |
+			uint64(binary.BigEndian.Uint16(id[6:8])&0x0fff)
+		return time.Unix(0, (int64(ts)-gregorianOffset)*100), true
+	case 7:
+		// milliseconds since the Unix epoch in the first 48 bits
+		ms := binary.BigEndian.Uint64(id[0:8]) >> 16
+		return time.UnixMilli(int64(ms)), true
+	}
+	return time.Time{}, false
+}
+
+// runInspect implements "uuid inspect [--json] <uuid>"
+func runInspect(args []string, w io.Writer) error {
+	fs := flag.NewFlagSet("inspect", flag.ExitOnError)
+	fs.Usage = func() {
+		fmt.Fprintln(fs.Output(), "Usage: uuid inspect [--json] <uuid>")
+		fs.PrintDefaults()
+	}
+	jsonOutput := fs.Bool("json", false, "print the contents as JSON")
+	fs.Parse(args)
+	if fs.NArg() < 1 {
+		fs.Usage()
+		return fmt.Errorf("missing UUID")
+	}
+	// flags after the UUID
+	input := fs.Arg(0)
+	fs.Parse(fs.Args()[1:])
+	if fs.NArg() > 0 {
+		return fmt.Errorf("expected a single UUID, got %s", strings.Join(append([]string{input}, fs.Args()...), " "))
+	}
+
+	id, err := decode(input, "auto")
+	if err != nil {
+		return fmt.Errorf("invalid UUID %q: %w", input, err)
+	}
+	i := inspect(id)
+
+	if *jsonOutput {
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(i)
+	}
+	fmt.Fprintf(w, "%-15s%s\n", "uuid", i.UUID)
+	fmt.Fprintf(w, "%-15s%d (%s)\n", "version", i.Version, i.VersionName)
+	fmt.Fprintf(w, "%-15s%s\n", "variant", i.Variant)
+	if i.Time != "" {
+		fmt.Fprintf(w, "%-15s%s\n", "time", i.Time)
+	}
+	if i.ClockSequence != nil {
+		fmt.Fprintf(w, "%-15s%d\n", "clock sequence", *i.ClockSequence)
+	}
+	if i.Node != "" {
+		fmt.Fprintf(w, "%-15s%s\n", "node", i.Node)
+	}
+	return nil
+}
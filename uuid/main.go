@@ -1,16 +1,63 @@
 package main
 
 import (
+	"bufio"
 	"flag"
 	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
 
 	"github.com/google/uuid"
 )
 
+// namespaces are the predefined namespaces of RFC 9562 for name-based UUIDs
+var namespaces = map[string]uuid.UUID{
+	"dns":  uuid.NameSpaceDNS,
+	"url":  uuid.NameSpaceURL,
+	"oid":  uuid.NameSpaceOID,
+	"x500": uuid.NameSpaceX500,
+}
+
+// parseNamespace returns a predefined namespace by name or a custom namespace given as UUID
+func parseNamespace(s string) (uuid.UUID, error) {
+	if ns, ok := namespaces[strings.ToLower(s)]; ok {
+		return ns, nil
+	}
+	ns, err := uuid.Parse(s)
+	if err != nil {
+		return uuid.Nil, fmt.Errorf("invalid namespace %q, expected dns, url, oid, x500 or a UUID", s)
+	}
+	return ns, nil
+}
+
 func main() {
+	if len(os.Args) > 1 {
+		commands := map[string]func([]string, io.Writer) error{
+			"inspect": runInspect,
+			"convert": runConvert,
+		}
+		if run, ok := commands[os.Args[1]]; ok {
+			if err := run(os.Args[2:], os.Stdout); err != nil {
+				fmt.Fprintln(os.Stderr, err)
+				os.Exit(1)
+			}
+			return
+		}
+	}
 
 	count := flag.Int("n", 1, "number of UUIDs to generate")
 	verify := flag.String("v", "", "verify a UUID")
+	version := flag.String("version", "4", "UUID version to generate: 3, 4, 5, 6, 7, ulid or random (128 random bits)")
+	namespace := flag.String("namespace", "", "namespace for version 3 and 5: dns, url, oid, x500 or a UUID")
+	name := flag.String("name", "", "name for version 3 and 5")
+	format := flag.String("format", "", "output format: "+strings.Join(formats, ", ")+" (default standard, base32 for ulid)")
+	list := flag.String("list", "lines", "how the IDs are listed: lines, json (an array) or csv (a column)")
+	output := flag.String("o", "", "write the IDs to this file instead of stdout")
+	seed := flag.String("seed", "", "seed for deterministic IDs, the clock of time-based versions stops at --time or 2000-01-01")
+	fixedTime := flag.String("time", "", "fixed time for time-based versions, RFC 3339")
 
 	flag.Parse()
 
@@ -18,13 +65,109 @@ func main() {
 		_, err := uuid.Parse(*verify)
 		if err != nil {
 			fmt.Println("invalid")
-			return
+			os.Exit(1)
 		}
 		fmt.Println("valid")
 		return
 	}
 
-	for i := 0; i < *count; i++ {
-		fmt.Println(uuid.NewString())
+	var seedValue *uint64
+	if *seed != "" {
+		v, err := strconv.ParseUint(*seed, 10, 64)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid seed %q, expected a number\n", *seed)
+			os.Exit(1)
+		}
+		seedValue = &v
+	}
+	var start time.Time
+	if *fixedTime != "" {
+		t, err := time.Parse(time.RFC3339Nano, *fixedTime)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "invalid time %q, expected RFC 3339 like 2024-01-01T00:00:00Z\n", *fixedTime)
+			os.Exit(1)
+		}
+		start = t
+	}
+	g, err := newGenerator(*version, *namespace, *name, seedValue, start)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	if *format == "" {
+		*format = "standard"
+		if *version == "ulid" {
+			*format = "ulid"
+		}
+	}
+	if _, err := encode(uuid.Nil, *format); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+
+	out := os.Stdout
+	if *output != "" {
+		if out, err = os.Create(*output); err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			os.Exit(1)
+		}
+	}
+	err = writeIDs(out, g, *count, *format, *list)
+	if *output != "" {
+		if closeErr := out.Close(); err == nil {
+			err = closeErr
+		}
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+}
+
+// writeIDs generates count IDs and writes them through a buffer as lines, a JSON array or a CSV column
+func writeIDs(out io.Writer, g *generator, count int, format, list string) error {
+	var prefix, separator, suffix string
+	quote := false
+	switch list {
+	case "lines":
+		separator, suffix = "\n", "\n"
+	case "json":
+		prefix, separator, suffix, quote = "[\n  ", ",\n  ", "\n]\n", true
+		if count == 0 {
+			prefix = "["
+		}
+	case "csv":
+		prefix, separator, suffix = "id\n", "\n", "\n"
+	default:
+		return fmt.Errorf("unknown list %q, expected lines, json or csv", list)
+	}
+
+	w := bufio.NewWriterSize(out, 64*1024)
+	w.WriteString(prefix)
+	for i := 0; i < count; i++ {
+		id, err := g.next()
+		if err != nil {
+			return err
+		}
+		s, err := encode(id, format)
+		if err != nil {
+			return err
+		}
+		if i > 0 {
+			w.WriteString(separator)
+		}
+		if quote {
+			// none of the formats contain characters that need escaping
+			w.WriteByte('"')
+			w.WriteString(s)
+			w.WriteByte('"')
+		} else {
+			w.WriteString(s)
+		}
+	}
+	if count > 0 || list == "json" {
+		w.WriteString(suffix)
 	}
+	return w.Flush()
 }
@@ -0,0 +1,430 @@
+package main
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"os"
+	"os/exec"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// TestMain runs main instead of the tests when the test binary is started by runMain
+func TestMain(m *testing.M) {
+	if os.Getenv("UUID_TEST_MAIN") == "1" {
+		os.Args = append([]string{"uuid"}, os.Args[1:]...)
+		main()
+		os.Exit(0)
+	}
+	os.Exit(m.Run())
+}
+
+// runMain runs the command with args and returns its stdout and exit code
+func runMain(t *testing.T, args ...string) (string, int) {
+	t.Helper()
+	cmd := exec.Command(os.Args[0], args...)
+	cmd.Env = append(os.Environ(), "UUID_TEST_MAIN=1")
+	out, err := cmd.Output()
+	if exitErr, ok := err.(*exec.ExitError); ok {
+		return string(out), exitErr.ExitCode()
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return string(out), 0
+}
+
+// newID generates a single ID with the clock and randomness of a normal run
+func newID(version, namespace, name string) (uuid.UUID, error) {
+	g, err := newGenerator(version, namespace, name, nil, time.Time{})
+	if err != nil {
+		return uuid.Nil, err
+	}
+	return g.next()
+}
+
+func TestNameBased(t *testing.T) {
+	tests := []struct {
+		version         string
+		namespace, name string
+		want            string
+	}{
+		// RFC 9562, appendix A.2 and A.4
+		{"3", "dns", "www.example.com", "5df41881-3aed-3515-88a7-2f4a814cf09e"},
+		{"5", "dns", "www.example.com", "2ed6657d-e927-568b-95e1-2665a8aea6a2"},
+		{"5", "dns", "example.com", "cfbff0d1-9375-5685-968c-48ce8b15ae17"},
+		{"5", "DNS", "example.com", "cfbff0d1-9375-5685-968c-48ce8b15ae17"},
+		{"5", "6ba7b810-9dad-11d1-80b4-00c04fd430c8", "example.com", "cfbff0d1-9375-5685-968c-48ce8b15ae17"},
+	}
+	for _, tt := range tests {
+		id, err := newID(tt.version, tt.namespace, tt.name)
+		if err != nil {
+			t.Fatalf("newID(%s, %s, %s): %v", tt.version, tt.namespace, tt.name, err)
+		}
+		if id.String() != tt.want {
+			t.Errorf("version %s of %s/%s = %s, want %s", tt.version, tt.namespace, tt.name, id, tt.want)
+		}
+	}
+}
+
+func TestVersions(t *testing.T) {
+	for _, version := range []string{"4", "6", "7", "ulid", "random"} {
+		seen := map[uuid.UUID]bool{}
+		for i := 0; i < 100; i++ {
+			id, err := newID(version, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(version) == 1 && (id.Version() != uuid.Version(version[0]-'0') || id.Variant() != uuid.RFC4122) {
+				t.Fatalf("version %s: %s has version %d and variant %s", version, id, id.Version(), id.Variant())
+			}
+			if seen[id] {
+				t.Fatalf("version %s: duplicate %s", version, id)
+			}
+			seen[id] = true
+		}
+	}
+}
+
+func TestNewUUIDErrors(t *testing.T) {
+	tests := []struct {
+		version         string
+		namespace, name string
+	}{
+		{"3", "dns", ""},
+		{"5", "", "example.com"},
+		{"5", "example", "example.com"},
+		{"1", "", ""},
+		{"8", "", ""},
+		{"ULID", "", ""},
+	}
+	for _, tt := range tests {
+		if _, err := newID(tt.version, tt.namespace, tt.name); err == nil {
+			t.Errorf("newID(%s, %q, %q): expected an error", tt.version, tt.namespace, tt.name)
+		}
+	}
+}
+
+func TestMonotonic(t *testing.T) {
+	start := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	// more IDs than the 12 bit counter of version 7 holds within one millisecond
+	const count = 10000
+	for _, version := range []string{"6", "7", "ulid"} {
+		g, err := newGenerator(version, "", "", nil, start)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var last uuid.UUID
+		lastText := ""
+		for i := 0; i < count; i++ {
+			id, err := g.next()
+			if err != nil {
+				t.Fatal(err)
+			}
+			text, _ := encode(id, "base32")
+			if i > 0 && (bytes.Compare(last[:], id[:]) >= 0 || lastText >= text) {
+				t.Fatalf("version %s: ID %d %s is not greater than %s", version, i, id, last)
+			}
+			if version != "ulid" && (id.Version() != uuid.Version(version[0]-'0') || id.Variant() != uuid.RFC4122) {
+				t.Fatalf("version %s: %s has version %d and variant %s", version, id, id.Version(), id.Variant())
+			}
+			last, lastText = id, text
+		}
+
+		// the clock does not move, the timestamp only moves on when the counter overflows
+		ts, _ := timestamp(last)
+		if version == "ulid" {
+			ts = time.UnixMilli(int64(last[0])<<40 | int64(last[1])<<32 | int64(last[2])<<24 | int64(last[3])<<16 | int64(last[4])<<8 | int64(last[5]))
+		}
+		if d := ts.Sub(start); d < 0 || d > 10*time.Millisecond {
+			t.Errorf("version %s: timestamp %s drifted from %s", version, ts.UTC(), start)
+		}
+	}
+}
+
+func TestSeed(t *testing.T) {
+	for _, version := range []string{"4", "6", "7", "ulid", "random"} {
+		run := func(seed uint64) string {
+			g, err := newGenerator(version, "", "", &seed, time.Time{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			var out bytes.Buffer
+			if err := writeIDs(&out, g, 100, "standard", "lines"); err != nil {
+				t.Fatal(err)
+			}
+			return out.String()
+		}
+		first, second := run(42), run(42)
+		if first != second {
+			t.Errorf("version %s: two runs with the same seed differ:\n%s\n%s", version, first, second)
+		}
+		if strings.Count(first, "\n") != 100 {
+			t.Errorf("version %s: expected 100 lines, got:\n%s", version, first)
+		}
+		if other := run(43); other == first {
+			t.Errorf("version %s: different seeds give the same IDs", version)
+		}
+	}
+}
+
+func TestInspect(t *testing.T) {
+	// the examples of RFC 9562, appendix A, all at 2022-02-22 14:22:22 -05:00
+	tests := []struct {
+		id       string
+		version  int
+		time     string
+		clockSeq int
+		node     string
+	}{
+		{"c232ab00-9414-11ec-b3c8-9f6bdeced846", 1, "2022-02-22T19:22:22Z", 13256, "9f6bdeced846"},
+		{"1ec9414c-232a-6b00-b3c8-9f6bdeced846", 6, "2022-02-22T19:22:22Z", 13256, "9f6bdeced846"},
+		{"017f22e2-79b0-7cc3-98c4-dc0c0c07398f", 7, "2022-02-22T19:22:22Z", -1, ""},
+		{"6ba7b810-9dad-11d1-80b4-00c04fd430c8", 1, "1998-02-04T22:13:53.1511824Z", 180, "00c04fd430c8"},
+		{"2ed6657d-e927-568b-95e1-2665a8aea6a2", 5, "", -1, ""},
+	}
+	for _, tt := range tests {
+		i := inspect(uuid.MustParse(tt.id))
+		if i.Version != tt.version || i.Variant != "RFC4122" {
+			t.Errorf("%s: version %d variant %s", tt.id, i.Version, i.Variant)
+		}
+		if i.Time != tt.time {
+			t.Errorf("%s: time %q, want %q", tt.id, i.Time, tt.time)
+		}
+		if tt.clockSeq < 0 {
+			if i.ClockSequence != nil || i.Node != "" {
+				t.Errorf("%s: unexpected clock sequence or node %+v", tt.id, i)
+			}
+			continue
+		}
+		if i.ClockSequence == nil || *i.ClockSequence != tt.clockSeq {
+			t.Errorf("%s: clock sequence %v, want %d", tt.id, i.ClockSequence, tt.clockSeq)
+		}
+		if i.Node != tt.node {
+			t.Errorf("%s: node %s, want %s", tt.id, i.Node, tt.node)
+		}
+	}
+
+	for _, id := range []uuid.UUID{uuid.Nil, uuid.Max} {
+		if i := inspect(id); i.Time != "" || i.ClockSequence != nil || i.Node != "" {
+			t.Errorf("%s: unexpected contents %+v", id, i)
+		}
+	}
+
+	// generated time-based IDs carry the current time
+	for _, version := range []string{"6", "7"} {
+		before := time.Now().Add(-time.Second)
+		id, err := newID(version, "", "")
+		if err != nil {
+			t.Fatal(err)
+		}
+		ts, err := time.Parse(time.RFC3339Nano, inspect(id).Time)
+		if err != nil {
+			t.Fatalf("version %s: %v", version, err)
+		}
+		if ts.Before(before) || ts.After(time.Now().Add(time.Second)) {
+			t.Errorf("version %s: time %s is not now", version, ts)
+		}
+	}
+}
+
+func TestRunInspect(t *testing.T) {
+	want := `uuid           c232ab00-9414-11ec-b3c8-9f6bdeced846
+version        1 (time-based)
+variant        RFC4122
+time           2022-02-22T19:22:22Z
+clock sequence 13256
+node           9f6bdeced846
+`
+	for _, input := range []string{
+		"c232ab00-9414-11ec-b3c8-9f6bdeced846",
+		"C232AB00-9414-11EC-B3C8-9F6BDECED846",
+		"c232ab00941411ecb3c89f6bdeced846",
+		"{c232ab00-9414-11ec-b3c8-9f6bdeced846}",
+		"urn:uuid:c232ab00-9414-11ec-b3c8-9f6bdeced846",
+		" c232ab00-9414-11ec-b3c8-9f6bdeced846\n",
+	} {
+		var out bytes.Buffer
+		if err := runInspect([]string{input}, &out); err != nil {
+			t.Errorf("inspect %q: %v", input, err)
+			continue
+		}
+		if out.String() != want {
+			t.Errorf("inspect %q:\n%s\nwant:\n%s", input, out.String(), want)
+		}
+	}
+
+	var out bytes.Buffer
+	if err := runInspect([]string{"017f22e2-79b0-7cc3-98c4-dc0c0c07398f", "--json"}, &out); err != nil {
+		t.Fatal(err)
+	}
+	var got map[string]any
+	if err := json.Unmarshal(out.Bytes(), &got); err != nil {
+		t.Fatal(err)
+	}
+	if got["version"] != 7.0 || got["time"] != "2022-02-22T19:22:22Z" || got["variant"] != "RFC4122" {
+		t.Errorf("unexpected JSON %s", out.String())
+	}
+	if _, ok := got["clock_sequence"]; ok {
+		t.Errorf("version 7 has no clock sequence: %s", out.String())
+	}
+
+	for _, args := range [][]string{{"not-a-uuid"}, {"c232ab00-9414-11ec-b3c8"}, {}, {"c232ab00-9414-11ec-b3c8-9f6bdeced846", "extra"}} {
+		if err := runInspect(args, &bytes.Buffer{}); err == nil {
+			t.Errorf("inspect %q: expected an error", args)
+		}
+	}
+}
+
+func TestExitCodes(t *testing.T) {
+	tests := []struct {
+		args []string
+		out  string
+		code int
+	}{
+		{[]string{"inspect", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, "uuid           6ba7b810-9dad-11d1-80b4-00c04fd430c8\nversion        1 (time-based)\nvariant        RFC4122\ntime           1998-02-04T22:13:53.1511824Z\nclock sequence 180\nnode           00c04fd430c8\n", 0},
+		{[]string{"inspect", "nope"}, "", 1},
+		{[]string{"-v", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, "valid\n", 0},
+		{[]string{"-v", "nope"}, "invalid\n", 1},
+	}
+	for _, tt := range tests {
+		out, code := runMain(t, tt.args...)
+		if code != tt.code || out != tt.out {
+			t.Errorf("uuid %s: exit code %d output %q, want %d and %q", strings.Join(tt.args, " "), code, out, tt.code, tt.out)
+		}
+	}
+}
+
+func TestFormats(t *testing.T) {
+	ids := []uuid.UUID{uuid.Nil, uuid.Max, uuid.MustParse("00000000-0000-0000-0000-000000000001"), uuid.MustParse("00ff0000-0000-0000-0000-000000000000")}
+	for _, version := range []string{"4", "7", "ulid", "random"} {
+		for i := 0; i < 50; i++ {
+			id, err := newID(version, "", "")
+			if err != nil {
+				t.Fatal(err)
+			}
+			ids = append(ids, id)
+		}
+	}
+
+	for _, id := range ids {
+		for _, format := range append(formats, "ulid") {
+			s, err := encode(id, format)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got, err := decode(s, format)
+			if err != nil {
+				t.Fatalf("decode(%q, %s): %v", s, format, err)
+			}
+			if got != id {
+				t.Errorf("%s round trip of %s gave %s via %q", format, id, got, s)
+			}
+		}
+	}
+
+	tests := []struct {
+		id     uuid.UUID
+		format string
+		want   string
+	}{
+		{uuid.Nil, "base32", "00000000000000000000000000"},
+		{uuid.Max, "base32", "7ZZZZZZZZZZZZZZZZZZZZZZZZZ"},
+		{uuid.Nil, "base58", "1111111111111111"},
+		{uuid.Max, "base58", "YcVfxkQb6JRzqk5kF2tNLv"},
+		{uuid.Nil, "base64url", "AAAAAAAAAAAAAAAAAAAAAA"},
+		{uuid.Max, "base64url", "_____________________w"},
+		{uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), "hex", "6ba7b8109dad11d180b400c04fd430c8"},
+		{uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), "urn", "urn:uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8"},
+		{uuid.MustParse("6ba7b810-9dad-11d1-80b4-00c04fd430c8"), "upper", "6BA7B810-9DAD-11D1-80B4-00C04FD430C8"},
+	}
+	for _, tt := range tests {
+		if got, _ := encode(tt.id, tt.format); got != tt.want {
+			t.Errorf("encode(%s, %s) = %q, want %q", tt.id, tt.format, got, tt.want)
+		}
+	}
+
+	// ULIDs sort like the bytes they encode
+	a, b := uuid.MustParse("01890a5d-ac96-774b-bcce-b302099a8057"), uuid.MustParse("01890a5d-ac97-0000-0000-000000000000")
+	if ea, eb := encodeBase32(a), encodeBase32(b); ea >= eb {
+		t.Errorf("%s sorts after %s", ea, eb)
+	}
+
+	// Crockford look-alikes and lower case decode to the same ULID
+	want, err := decode("01ARZ3NDEKTSV4RRFFQ69G5FAV", "ulid")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, err := decode("oiarz3ndektsv4rrffq69g5fav", "ulid"); err != nil || got != want {
+		t.Errorf("decoding look-alikes gave %s, %v, want %s", got, err, want)
+	}
+
+	for _, tt := range []struct{ s, format string }{
+		{"8ZZZZZZZZZZZZZZZZZZZZZZZZZ", "base32"},
+		{"0000000000000000000000000", "base32"},
+		{"0000000000000000000000000U", "base32"},
+		{"", "base58"},
+		{"0OIl", "base58"},
+		{"zzzzzzzzzzzzzzzzzzzzzzzzz", "base58"},
+		{"AAAA", "base64url"},
+		{"AAAAAAAAAAAAAAAAAAAAAA==", "base64url"},
+		{"CgBwY7jMTP4a3uybZKUgs", "auto"},
+		{"c232ab00", "auto"},
+		{"AAAA", "base64"},
+	} {
+		if id, err := decode(tt.s, tt.format); err == nil {
+			t.Errorf("decode(%q, %s) = %s, expected an error", tt.s, tt.format, id)
+		}
+	}
+}
+
+func TestULIDTime(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 123456789, time.UTC)
+	g, err := newGenerator("ulid", "", "", nil, now)
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, err := g.next()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := hex.EncodeToString(id[:6]); got != "018f34069e7b" {
+		t.Errorf("timestamp bytes %s, want 018f34069e7b", got)
+	}
+	if s := encodeBase32(id); !strings.HasPrefix(s, "01HWT0D7KV") {
+		t.Errorf("ULID %s does not start with the encoded time 01HWT0D7KV", s)
+	}
+}
+
+func TestRunConvert(t *testing.T) {
+	tests := []struct {
+		args []string
+		want string
+	}{
+		{[]string{"--to", "base58", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, "EJ34kCVxxF9jHMKD4EgrAK\n"},
+		{[]string{"--from", "base58", "EJ34kCVxxF9jHMKD4EgrAK"}, "6ba7b810-9dad-11d1-80b4-00c04fd430c8\n"},
+		{[]string{"--to", "ulid", "00000000-0000-0000-0000-000000000000", "ffffffff-ffff-ffff-ffff-ffffffffffff"}, "00000000000000000000000000\n7ZZZZZZZZZZZZZZZZZZZZZZZZZ\n"},
+		{[]string{"7ZZZZZZZZZZZZZZZZZZZZZZZZZ", "--to", "hex"}, "ffffffffffffffffffffffffffffffff\n"},
+		{[]string{"--from", "base64url", "--to", "urn", "a6e4EJ2tEdGAtADAT9QwyA"}, "urn:uuid:6ba7b810-9dad-11d1-80b4-00c04fd430c8\n"},
+	}
+	for _, tt := range tests {
+		var out bytes.Buffer
+		if err := runConvert(tt.args, &out); err != nil {
+			t.Errorf("convert %q: %v", tt.args, err)
+			continue
+		}
+		if out.String() != tt.want {
+			t.Errorf("convert %q = %q, want %q", tt.args, out.String(), tt.want)
+		}
+	}
+
+	for _, args := range [][]string{{"--from", "base58", ""}, {"--to", "base99", "6ba7b810-9dad-11d1-80b4-00c04fd430c8"}, {"nope"}} {
+		if err := runConvert(args, &bytes.Buffer{}); err == nil {
+			t.Errorf("convert %q: expected an error", args)
+		}
+	}
+}